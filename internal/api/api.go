@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,16 +19,17 @@ import (
 func Init() {
 	var cfg struct {
 		Mod struct {
-			Listen     string `yaml:"listen"`
-			Username   string `yaml:"username"`
-			Password   string `yaml:"password"`
-			BasePath   string `yaml:"base_path"`
-			StaticDir  string `yaml:"static_dir"`
-			Origin     string `yaml:"origin"`
-			TLSListen  string `yaml:"tls_listen"`
-			TLSCert    string `yaml:"tls_cert"`
-			TLSKey     string `yaml:"tls_key"`
-			UnixListen string `yaml:"unix_listen"`
+			Listen     string  `yaml:"listen"`
+			Username   string  `yaml:"username"`
+			Password   string  `yaml:"password"`
+			Users      []*User `yaml:"users"`
+			BasePath   string  `yaml:"base_path"`
+			StaticDir  string  `yaml:"static_dir"`
+			Origin     string  `yaml:"origin"`
+			TLSListen  string  `yaml:"tls_listen"`
+			TLSCert    string  `yaml:"tls_cert"`
+			TLSKey     string  `yaml:"tls_key"`
+			UnixListen string  `yaml:"unix_listen"`
 		} `yaml:"api"`
 	}
 
@@ -39,6 +39,10 @@ func Init() {
 	// load config from YAML
 	app.LoadConfig(&cfg)
 
+	// tokens and auth hook also used by RTSP and RTMP servers
+	initTokens()
+	initAuthHook()
+
 	if cfg.Mod.Listen == "" && cfg.Mod.UnixListen == "" && cfg.Mod.TLSListen == "" {
 		return
 	}
@@ -53,15 +57,34 @@ func Init() {
 	HandleFunc("api/exit", exitHandler)
 	HandleFunc("api/restart", restartHandler)
 	HandleFunc("api/log", logHandler)
+	HandleFunc("api/tokens", apiTokens)
+
+	initHealth()
 
-	Handler = http.DefaultServeMux // 4th
+	Handler = http.DefaultServeMux // 5th
 
 	if cfg.Mod.Origin == "*" {
-		Handler = middlewareCORS(Handler) // 3rd
+		Handler = middlewareCORS(Handler) // 4th
+	}
+
+	handler := Handler // without auth for token requests
+
+	initUsers(cfg.Mod.Username, cfg.Mod.Password, cfg.Mod.Users)
+
+	if authHookURL != "" {
+		hasAuth = true
+		Handler = middlewareAuthHook(Handler) // 3rd
+	} else if users != nil {
+		hasAuth = true
+		Handler = middlewareAuth(Handler) // 3rd
+	}
+
+	if tokenSecret != nil {
+		Handler = middlewareToken(Handler, handler) // 2nd
 	}
 
-	if cfg.Mod.Username != "" {
-		Handler = middlewareAuth(cfg.Mod.Username, cfg.Mod.Password, Handler) // 2nd
+	if hasAuth || tokenSecret != nil {
+		Handler = middlewareHealth(Handler, handler) // health checks without auth
 	}
 
 	if log.Trace().Enabled() {
@@ -69,22 +92,26 @@ func Init() {
 	}
 
 	if cfg.Mod.Listen != "" {
+		setListener(cfg.Mod.Listen, errNotBound)
 		go listen("tcp", cfg.Mod.Listen)
 	}
 
 	if cfg.Mod.UnixListen != "" {
 		_ = syscall.Unlink(cfg.Mod.UnixListen)
+		setListener(cfg.Mod.UnixListen, errNotBound)
 		go listen("unix", cfg.Mod.UnixListen)
 	}
 
 	// Initialize the HTTPS server
 	if cfg.Mod.TLSListen != "" && cfg.Mod.TLSCert != "" && cfg.Mod.TLSKey != "" {
+		setListener(cfg.Mod.TLSListen, errNotBound)
 		go tlsListen("tcp", cfg.Mod.TLSListen, cfg.Mod.TLSCert, cfg.Mod.TLSKey)
 	}
 }
 
 func listen(network, address string) {
 	ln, err := net.Listen(network, address)
+	setListener(address, err)
 	if err != nil {
 		log.Error().Err(err).Msg("[api] listen")
 		return
@@ -116,11 +143,13 @@ func tlsListen(network, address, certFile, keyFile string) {
 		cert, err = tls.X509KeyPair([]byte(certFile), []byte(keyFile))
 	}
 	if err != nil {
+		setListener(address, err)
 		log.Error().Err(err).Caller().Send()
 		return
 	}
 
 	ln, err := net.Listen(network, address)
+	setListener(address, err)
 	if err != nil {
 		log.Error().Err(err).Msg("[api] tls listen")
 		return
@@ -188,6 +217,7 @@ func Response(w http.ResponseWriter, body any, contentType string) {
 const StreamNotFound = "stream not found"
 
 var basePath string
+var hasAuth bool
 var log zerolog.Logger
 
 func middlewareLog(next http.Handler) http.Handler {
@@ -197,15 +227,24 @@ func middlewareLog(next http.Handler) http.Handler {
 	})
 }
 
-func middlewareAuth(username, password string, next http.Handler) http.Handler {
+// middlewareAuth - users with password or bearer token, role checked for each request
+func middlewareAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.HasPrefix(r.RemoteAddr, "127.") && !strings.HasPrefix(r.RemoteAddr, "[::1]") && r.RemoteAddr != "@" {
-			user, pass, ok := r.BasicAuth()
-			if !ok || user != username || pass != password {
+		if !isLoopback(r.RemoteAddr) {
+			user := findUser(r)
+			if user == nil {
 				w.Header().Set("Www-Authenticate", `Basic realm="go2rtc"`)
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
+
+			role := user.role
+			if !checkRole(w, r, role) {
+				return
+			}
+
+			r = withSession(r, &Session{User: user.Username})
+			r = withRole(r, role)
 		}
 
 		next.ServeHTTP(w, r)
@@ -246,7 +285,7 @@ func exitHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	os.Exit(code)
+	go app.Shutdown(code)
 }
 
 func restartHandler(w http.ResponseWriter, r *http.Request) {
@@ -255,15 +294,9 @@ func restartHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	path, err := os.Executable()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	log.Debug().Msgf("[api] restart %s", path)
+	log.Debug().Msg("[api] restart")
 
-	go syscall.Exec(path, os.Args, os.Environ())
+	go app.Restart()
 }
 
 func logHandler(w http.ResponseWriter, r *http.Request) {
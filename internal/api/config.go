@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/pkg/shell"
 	"gopkg.in/yaml.v3"
 )
 
@@ -22,6 +23,8 @@ func configHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "", http.StatusNotFound)
 			return
 		}
+		// hide values resolved from ${file:...} and ${enc:...} templates
+		data = []byte(shell.MaskSecrets(string(data)))
 		// https://www.ietf.org/archive/id/draft-ietf-httpapi-yaml-mediatypes-00.html
 		Response(w, data, "application/yaml")
 
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+)
+
+var errNotBound = errors.New("not bound yet")
+
+// listeners - API listeners state by address, nil for bound listener
+var listeners = map[string]error{}
+var listenersMu sync.Mutex
+
+func setListener(address string, err error) {
+	listenersMu.Lock()
+	listeners[address] = err
+	listenersMu.Unlock()
+}
+
+func initHealth() {
+	app.AddHealthCheck("api", func() error {
+		listenersMu.Lock()
+		defer listenersMu.Unlock()
+		for address, err := range listeners {
+			if err != nil {
+				return errors.New(address + ": " + err.Error())
+			}
+		}
+		return nil
+	})
+
+	HandleFunc("api/health", healthHandler)
+	HandleFunc("api/ready", readyHandler)
+}
+
+// healthPaths - probes of orchestrators don't need auth
+var healthPaths = map[string]bool{
+	"/api/health": true,
+	"/api/ready":  true,
+}
+
+func middlewareHealth(auth, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && healthPaths[strings.TrimPrefix(r.URL.Path, basePath)] {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		auth.ServeHTTP(w, r)
+	})
+}
+
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	responseChecks(w, app.HealthChecks())
+}
+
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	responseChecks(w, app.ReadyChecks())
+}
+
+// responseChecks - 200 if all checks passed, 503 otherwise
+func responseChecks(w http.ResponseWriter, results map[string]error) {
+	status := http.StatusOK
+	checks := make(map[string]string, len(results))
+
+	for name, err := range results {
+		if err != nil {
+			checks[name] = err.Error()
+			status = http.StatusServiceUnavailable
+		} else {
+			checks[name] = "ok"
+		}
+	}
+
+	body := map[string]any{"status": "ok", "checks": checks}
+	if status != http.StatusOK {
+		body["status"] = "fail"
+	}
+
+	ResponseStatusJSON(w, status, body)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	auth := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+	initHealth()
+
+	handler := middlewareHealth(auth, http.HandlerFunc(healthHandler))
+
+	setListener(":1984", errNotBound)
+	defer func() { listeners = map[string]error{} }()
+
+	// probes skip auth
+	r := httptest.NewRequest("GET", "/api/health", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Contains(t, w.Body.String(), `"status":"fail"`)
+	require.Contains(t, w.Body.String(), `":1984: not bound yet"`)
+
+	setListener(":1984", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"status":"ok"`)
+
+	r = httptest.NewRequest("GET", "/api/config", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
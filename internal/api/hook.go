@@ -0,0 +1,183 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// AuthRequest - body of the external auth hook request
+type AuthRequest struct {
+	Protocol   string `json:"protocol"` // http, ws, rtsp, rtmp
+	Action     string `json:"action"`   // play, publish, api
+	Stream     string `json:"stream,omitempty"`
+	Path       string `json:"path,omitempty"` // only for HTTP
+	RemoteAddr string `json:"remote_addr"`
+	Username   string `json:"username,omitempty"`
+	Password   string `json:"password,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+}
+
+// Limits - optional per session limits from the auth hook response
+type Limits struct {
+	Priority    string `json:"priority,omitempty"`     // viewer, operator, recorder
+	MaxDuration int    `json:"max_duration,omitempty"` // seconds
+	Role        string `json:"role,omitempty"`         // viewer (default), operator, admin
+}
+
+var authHookURL string
+var authHookClient *http.Client
+
+func initAuthHook() {
+	var cfg struct {
+		Mod struct {
+			URL     string        `yaml:"url"`
+			Timeout time.Duration `yaml:"timeout"`
+		} `yaml:"auth_hook"`
+	}
+
+	cfg.Mod.Timeout = 5 * time.Second
+
+	app.LoadConfig(&cfg)
+
+	if cfg.Mod.URL != "" {
+		authHookURL = cfg.Mod.URL
+		authHookClient = &http.Client{Timeout: cfg.Mod.Timeout}
+	}
+}
+
+func AuthHookEnabled() bool {
+	return authHookURL != ""
+}
+
+// AuthHook - ask external service about access, any non 2xx response denies access
+func AuthHook(req *AuthRequest) (*Limits, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := authHookClient.Post(authHookURL, MimeJSON, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, errors.New("api: auth hook denied, status " + strconv.Itoa(res.StatusCode))
+	}
+
+	// response body is optional
+	limits := &Limits{}
+	if b, _ := io.ReadAll(res.Body); len(b) > 0 {
+		if err = json.Unmarshal(b, limits); err != nil {
+			return nil, err
+		}
+	}
+
+	return limits, nil
+}
+
+// Session - credentials and limits of the authorized connection
+type Session struct {
+	User   string // username from basic auth, auth hook or token subject
+	Token  string // access token ID
+	Limits *Limits
+}
+
+// withSession - session in the request context, consumers get it with core.Connection.WithRequest
+func withSession(r *http.Request, session *Session) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), core.SessionKey{}, session))
+}
+
+// GetSession - session of the consumer connection, nil if connection wasn't authorized
+func GetSession(conn *core.Connection) *Session {
+	session, _ := conn.Session.(*Session)
+	return session
+}
+
+// SessionLimits - limits of the consumer connection, nil if there are no limits
+func SessionLimits(conn *core.Connection) *Limits {
+	if session := GetSession(conn); session != nil {
+		return session.Limits
+	}
+	return nil
+}
+
+// middlewareAuthHook - static users or the auth hook for all other requests
+func middlewareAuthHook(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isLoopback(r.RemoteAddr) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if u := findUser(r); u != nil {
+			role := u.role
+			if !checkRole(w, r, role) {
+				return
+			}
+
+			r = withSession(r, &Session{User: u.Username})
+
+			next.ServeHTTP(w, withRole(r, role))
+			return
+		}
+
+		user, pass, _ := r.BasicAuth()
+
+		req := &AuthRequest{
+			Protocol:   "http",
+			Action:     "api",
+			Path:       strings.TrimPrefix(r.URL.Path, basePath),
+			RemoteAddr: r.RemoteAddr,
+			Username:   user,
+			Password:   pass,
+			UserAgent:  r.UserAgent(),
+		}
+
+		if r.Header.Get("Upgrade") == "websocket" {
+			req.Protocol = "ws"
+		}
+
+		query := r.URL.Query()
+		if _, ok := tokenFormats[req.Path]; ok {
+			if req.Stream = query.Get("dst"); req.Stream != "" {
+				req.Action = "publish"
+			} else if req.Stream = query.Get("src"); req.Stream != "" {
+				req.Action = "play"
+			}
+		}
+
+		limits, err := AuthHook(req)
+		if err != nil {
+			log.Debug().Err(err).Msgf("[api] auth hook url=%s remote=%s", req.Path, r.RemoteAddr)
+			w.Header().Set("Www-Authenticate", `Basic realm="go2rtc"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		role, err := ParseRole(limits.Role)
+		if err != nil {
+			log.Debug().Err(err).Msgf("[api] auth hook url=%s remote=%s", req.Path, r.RemoteAddr)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if !checkRole(w, r, role) {
+			return
+		}
+
+		r = withSession(r, &Session{User: user, Limits: limits})
+
+		next.ServeHTTP(w, withRole(r, role))
+	})
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthHook(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req AuthRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		if req.Username != "user1" || req.Password != "pass1" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if req.Path == "/api/stream.mp4" {
+			require.Equal(t, "play", req.Action)
+			require.Equal(t, "camera1", req.Stream)
+		}
+
+		_, _ = w.Write([]byte(`{"priority":"operator","max_duration":60}`))
+	}))
+	defer srv.Close()
+
+	authHookURL = srv.URL
+	authHookClient = srv.Client()
+	initUsers("admin", "admin", nil)
+	defer func() {
+		authHookURL = ""
+		authHookClient = nil
+		users = nil
+	}()
+
+	var session *Session
+	handler := middlewareAuthHook(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := &core.Connection{}
+		conn.WithRequest(r)
+		session = GetSession(conn)
+	}))
+
+	r := httptest.NewRequest("GET", "/api/stream.mp4?src=camera1", nil)
+	r.SetBasicAuth("user1", "pass1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "user1", session.User)
+	require.Equal(t, &Limits{Priority: "operator", MaxDuration: 60}, session.Limits)
+
+	// hook response without role is viewer
+	r = httptest.NewRequest("GET", "/api/config", nil)
+	r.SetBasicAuth("user1", "pass1")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	r = httptest.NewRequest("GET", "/api/stream.mp4?src=camera1", nil)
+	r.SetBasicAuth("user1", "wrong")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// static credentials don't need the hook, session isn't inherited from the same address
+	r = httptest.NewRequest("GET", "/api/streams", nil)
+	r.SetBasicAuth("admin", "admin")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "admin", session.User)
+	require.Nil(t, session.Limits)
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Role - API access level, each role includes all lower roles
+type Role byte
+
+const (
+	RoleViewer   Role = iota + 1 // read-only API and playback
+	RoleOperator                 // streams management, publish and recording
+	RoleAdmin                    // config, restart and exec-style sources
+)
+
+// ParseRole - empty role is viewer, unknown role is an error
+func ParseRole(s string) (Role, error) {
+	switch s {
+	case "", "viewer":
+		return RoleViewer, nil
+	case "operator":
+		return RoleOperator, nil
+	case "admin":
+		return RoleAdmin, nil
+	}
+	return 0, errors.New("api: unknown role: " + s)
+}
+
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleOperator:
+		return "operator"
+	}
+	return "admin"
+}
+
+// User - API user with password or bearer token for machine clients
+type User struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Token    string `yaml:"token"`
+	Role     string `yaml:"role"`
+
+	role Role
+}
+
+var users []*User
+
+// initUsers - single username from config is admin for compatibility,
+// users with unknown role are skipped
+func initUsers(username, password string, items []*User) {
+	if username != "" {
+		users = append(users, &User{Username: username, Password: password, role: RoleAdmin})
+	}
+	for _, user := range items {
+		if user.Username == "" && user.Token == "" {
+			continue
+		}
+		role, err := ParseRole(user.Role)
+		if err != nil {
+			log.Error().Err(err).Str("user", user.Username).Msg("[api] users")
+			continue
+		}
+		user.role = role
+		users = append(users, user)
+	}
+}
+
+// findUser - user by basic auth or bearer token, users with token can't use password
+func findUser(r *http.Request) *User {
+	if user, pass, ok := r.BasicAuth(); ok {
+		for _, u := range users {
+			if u.Token == "" && u.Username == user && equal(u.Password, pass) {
+				return u
+			}
+		}
+		return nil
+	}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return findTokenUser(token)
+	}
+
+	return nil
+}
+
+func findTokenUser(token string) *User {
+	for _, u := range users {
+		if u.Token != "" && equal(u.Token, token) {
+			return u
+		}
+	}
+	return nil
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+type roleKey struct{}
+
+func withRole(r *http.Request, role Role) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), roleKey{}, role))
+}
+
+// RequestRole - role of the authorized request, admin if API has no auth
+func RequestRole(r *http.Request) Role {
+	if role, ok := r.Context().Value(roleKey{}).(Role); ok {
+		return role
+	}
+	if RequestToken(r) != nil {
+		return RoleViewer
+	}
+	return RoleAdmin
+}
+
+// checkRole - forbid request if the role is lower than required for path, method or sources
+func checkRole(w http.ResponseWriter, r *http.Request, role Role) bool {
+	if required := requiredRole(r); role < required {
+		log.Debug().Msgf("[api] forbidden url=%s role=%s required=%s", r.URL.Path, role, required)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// pathRoles - paths with role higher than viewer
+var pathRoles = map[string]Role{
+	"/api/config":  RoleAdmin,
+	"/api/exit":    RoleAdmin,
+	"/api/restart": RoleAdmin,
+	"/api/log":     RoleAdmin,
+	"/api/tokens":  RoleAdmin,
+	"/api/audit":   RoleAdmin,
+	"/api/stack":   RoleAdmin,
+
+	"/api/consumers":       RoleOperator,
+	"/api/v2/consumers":    RoleOperator,
+	"/api/ffmpeg":          RoleOperator,
+	"/api/ffmpeg/devices":  RoleOperator,
+	"/api/ffmpeg/hardware": RoleOperator,
+	"/api/dvrip":           RoleOperator,
+	"/api/gopro":           RoleOperator,
+	"/api/hass":            RoleOperator,
+	"/api/homekit":         RoleOperator,
+	"/api/nest":            RoleOperator,
+	"/api/onvif":           RoleOperator,
+	"/api/roborock":        RoleOperator,
+	"/api/webtorrent":      RoleOperator,
+}
+
+func requiredRole(r *http.Request) Role {
+	path := strings.TrimPrefix(r.URL.Path, basePath)
+
+	role, ok := pathRoles[path]
+	if !ok {
+		role = RoleViewer
+
+		// streams management
+		if r.Method != "GET" && r.Method != "HEAD" && r.Method != "OPTIONS" {
+			if path == "/api/streams" || strings.HasPrefix(path, "/api/v2/") {
+				role = RoleOperator
+			}
+		}
+	}
+
+	query := r.URL.Query()
+
+	// publish to the stream
+	if query.Has("dst") {
+		role = max(role, RoleOperator)
+	}
+
+	for _, key := range []string{"src", "dst"} {
+		for _, source := range query[key] {
+			role = max(role, SourceRole(source))
+		}
+	}
+
+	return role
+}
+
+// execSchemes - sources that can run any command on the server
+var execSchemes = []string{"exec", "echo", "expr"}
+
+// SourceRole - stream name is available for viewers, sources create dynamic streams
+func SourceRole(source string) Role {
+	i := strings.IndexByte(source, ':')
+	if i <= 0 {
+		return RoleViewer
+	}
+
+	for _, scheme := range execSchemes {
+		if source[:i] == scheme {
+			return RoleAdmin
+		}
+	}
+
+	// FFmpeg raw args can read and write any file
+	if strings.Contains(source, "#raw=") {
+		return RoleAdmin
+	}
+
+	return RoleOperator
+}
+
+// MessageRole - role for WebSocket message type
+func MessageRole(msgType string) Role {
+	switch msgType {
+	case "topology":
+		return RoleOperator
+	}
+	return RoleViewer
+}
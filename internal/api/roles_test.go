@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoles(t *testing.T) {
+	initUsers("admin", "admin", []*User{
+		{Username: "oper", Password: "oper", Role: "operator"},
+		{Username: "view", Password: "view", Role: "viewer"},
+		{Username: "nvr", Token: "secret", Role: "operator"},
+		{Username: "guest", Password: "guest"},
+		{Username: "typo", Password: "typo", Role: "admn"},
+	})
+	defer func() { users = nil }()
+
+	var role Role
+	handler := middlewareAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role = RequestRole(r)
+	}))
+
+	request := func(method, url, user, token string) int {
+		r := httptest.NewRequest(method, url, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		} else if user != "" {
+			r.SetBasicAuth(user, user)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusUnauthorized, request("GET", "/api/streams", "", ""))
+	require.Equal(t, http.StatusUnauthorized, request("GET", "/api/streams", "", "wrong"))
+
+	// viewer: playback and read-only API
+	require.Equal(t, http.StatusOK, request("GET", "/api/stream.mp4?src=camera1", "view", ""))
+	require.Equal(t, RoleViewer, role)
+	require.Equal(t, http.StatusOK, request("GET", "/api/streams", "view", ""))
+	require.Equal(t, http.StatusForbidden, request("POST", "/api/exit", "view", ""))
+	require.Equal(t, http.StatusForbidden, request("GET", "/api/config", "view", ""))
+	require.Equal(t, http.StatusForbidden, request("PUT", "/api/streams?name=cam&src=rtsp://1.2.3.4/", "view", ""))
+	require.Equal(t, http.StatusForbidden, request("GET", "/api/stream.mp4?src=rtsp://1.2.3.4/", "view", ""))
+	require.Equal(t, http.StatusForbidden, request("POST", "/api/webrtc?dst=camera1", "view", ""))
+
+	// operator: streams management, but no exec sources
+	require.Equal(t, http.StatusOK, request("PUT", "/api/streams?name=cam&src=rtsp://1.2.3.4/", "oper", ""))
+	require.Equal(t, http.StatusOK, request("DELETE", "/api/v2/streams/cam", "", "secret"))
+	require.Equal(t, RoleOperator, role)
+	require.Equal(t, http.StatusForbidden, request("PUT", "/api/streams?name=cam&src=exec:ls", "oper", ""))
+	require.Equal(t, http.StatusForbidden, request("POST", "/api/restart", "", "secret"))
+
+	// token users can't use basic auth
+	require.Equal(t, http.StatusUnauthorized, request("GET", "/api/streams", "nvr", ""))
+
+	// missing role is viewer, unknown role isn't loaded
+	require.Equal(t, http.StatusOK, request("GET", "/api/streams", "guest", ""))
+	require.Equal(t, RoleViewer, role)
+	require.Equal(t, http.StatusForbidden, request("GET", "/api/config", "guest", ""))
+	require.Equal(t, http.StatusUnauthorized, request("GET", "/api/config", "typo", ""))
+
+	// admin from the legacy username and password
+	require.Equal(t, http.StatusOK, request("PUT", "/api/streams?name=cam&src=exec:ls", "admin", ""))
+	require.Equal(t, http.StatusOK, request("GET", "/api/config", "admin", ""))
+	require.Equal(t, RoleAdmin, role)
+}
+
+func TestSourceRole(t *testing.T) {
+	require.Equal(t, RoleViewer, SourceRole("camera1"))
+	require.Equal(t, RoleOperator, SourceRole("rtsp://192.168.1.123/stream"))
+	require.Equal(t, RoleOperator, SourceRole("ffmpeg:camera1#video=h264"))
+	require.Equal(t, RoleAdmin, SourceRole("ffmpeg:camera1#raw=-f null"))
+	require.Equal(t, RoleAdmin, SourceRole("exec:ffmpeg -i rtsp://1.2.3.4/ -f rtsp {output}"))
+	require.Equal(t, RoleAdmin, SourceRole("echo:/bin/cat /etc/passwd"))
+}
@@ -0,0 +1,299 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/jwt"
+)
+
+// Token - access token claims for watching one stream
+type Token struct {
+	ID      string   `json:"jti,omitempty"` // for audit, hash of the token if empty
+	Subject string   `json:"sub,omitempty"` // optional viewer name
+	Stream  string   `json:"stream"`
+	Formats []string `json:"formats,omitempty"` // empty - any format
+	IP      string   `json:"ip,omitempty"`      // IP or CIDR
+	Expires int64    `json:"exp,omitempty"`     // unix time
+}
+
+var (
+	ErrTokenRequired = errors.New("api: access token required")
+	ErrTokenExpired  = errors.New("api: access token expired")
+	ErrTokenStream   = errors.New("api: access token wrong stream")
+	ErrTokenFormat   = errors.New("api: access token wrong format")
+	ErrTokenIP       = errors.New("api: access token wrong IP")
+)
+
+var tokenSecret []byte
+var tokenRequired bool
+
+func initTokens() {
+	var cfg struct {
+		Mod struct {
+			Secret   string `yaml:"secret"`
+			Required bool   `yaml:"required"`
+		} `yaml:"tokens"`
+	}
+
+	app.LoadConfig(&cfg)
+
+	if cfg.Mod.Secret != "" {
+		tokenSecret = []byte(cfg.Mod.Secret)
+		tokenRequired = cfg.Mod.Required
+	}
+}
+
+// NewToken - sign access token with the secret from config
+func NewToken(token *Token) (string, error) {
+	if tokenSecret == nil {
+		return "", errors.New("api: tokens secret not configured")
+	}
+	for _, format := range token.Formats {
+		if !slices.Contains(tokenFormatNames, format) {
+			return "", errors.New("api: unknown token format: " + format)
+		}
+	}
+	return jwt.Encode(token, tokenSecret)
+}
+
+// ParseToken - check token signature and expiration
+func ParseToken(s string) (*Token, error) {
+	token := &Token{}
+	if err := jwt.Decode(s, tokenSecret, token); err != nil {
+		return nil, err
+	}
+	if token.Expires != 0 && time.Now().Unix() > token.Expires {
+		return nil, ErrTokenExpired
+	}
+	if token.ID == "" {
+		sum := sha256.Sum256([]byte(s))
+		token.ID = hex.EncodeToString(sum[:8])
+	}
+	return token, nil
+}
+
+// Allow - check token access to the stream with format from the remote address,
+// empty stream or format skips the check
+func (t *Token) Allow(stream, format, remoteAddr string) error {
+	if stream != "" && stream != t.Stream {
+		return ErrTokenStream
+	}
+
+	if format != "" && t.Formats != nil && !t.allowFormat(format) {
+		return ErrTokenFormat
+	}
+
+	if t.IP != "" && !matchIP(t.IP, remoteAddr) {
+		return ErrTokenIP
+	}
+
+	return nil
+}
+
+func (t *Token) allowFormat(format string) bool {
+	if slices.Contains(t.Formats, format) {
+		return true
+	}
+	// MSE over WebSocket is the same fragmented MP4 as the mp4 output
+	return format == "mse" && slices.Contains(t.Formats, "mp4")
+}
+
+// Authorize - check access token for the stream output, for RTSP, RTMP and other servers.
+// Returns the token if the client authorized by the token. Empty token isn't an error,
+// except when tokens are required for remote clients.
+func Authorize(token, stream, format, remoteAddr string) (*Token, error) {
+	if tokenSecret == nil {
+		return nil, nil
+	}
+
+	if token == "" {
+		if tokenRequired && !isLoopback(remoteAddr) {
+			return nil, ErrTokenRequired
+		}
+		return nil, nil
+	}
+
+	t, err := ParseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = t.Allow(stream, format, remoteAddr); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+type tokenKey struct{}
+
+// RequestToken - access token of the HTTP request if it was authorized by token
+func RequestToken(r *http.Request) *Token {
+	token, _ := r.Context().Value(tokenKey{}).(*Token)
+	return token
+}
+
+// middlewareToken - requests with valid token skip basic auth and go directly to the handler
+func middlewareToken(auth, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := requestToken(r)
+		// bearer tokens of API users are checked by the auth middleware
+		if s == "" || findTokenUser(s) != nil {
+			if tokenRequired && !hasAuth && !isLoopback(r.RemoteAddr) {
+				http.Error(w, ErrTokenRequired.Error(), http.StatusUnauthorized)
+				return
+			}
+			auth.ServeHTTP(w, r)
+			return
+		}
+
+		token, err := ParseToken(s)
+		if err == nil {
+			err = checkRequestToken(token, r)
+		}
+		if err != nil {
+			log.Debug().Err(err).Msgf("[api] token url=%s remote=%s", r.URL.Path, r.RemoteAddr)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		r = withSession(r, &Session{User: token.Subject, Token: token.ID})
+		r = r.WithContext(context.WithValue(r.Context(), tokenKey{}, token))
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func requestToken(r *http.Request) string {
+	if s := r.URL.Query().Get("token"); s != "" {
+		return s
+	}
+	if s, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return s
+	}
+	return ""
+}
+
+func checkRequestToken(token *Token, r *http.Request) error {
+	path := strings.TrimPrefix(r.URL.Path, basePath)
+
+	// HLS session URLs are protected with the session ID
+	if strings.HasPrefix(path, "/api/hls/") {
+		return token.Allow("", "hls", r.RemoteAddr)
+	}
+
+	format, ok := tokenFormats[path]
+	if !ok {
+		return errors.New("api: access token not allowed for " + path)
+	}
+
+	// tokens only for watching
+	query := r.URL.Query()
+	if query.Has("dst") {
+		return errors.New("api: access token not allowed for publish")
+	}
+
+	return token.Allow(query.Get("src"), format, r.RemoteAddr)
+}
+
+// tokenFormatNames - formats for access tokens, WebSocket message types use the same names
+var tokenFormatNames = []string{"webrtc", "mse", "mp4", "hls", "mjpeg", "mpegts", "rtsp", "rtmp"}
+
+// tokenFormats - output API paths available with access tokens,
+// WebSocket formats checked for each message
+var tokenFormats = map[string]string{
+	"/api/ws":           "",
+	"/api/webrtc":       "webrtc",
+	"/api/stream.mp4":   "mp4",
+	"/api/frame.mp4":    "mp4",
+	"/api/stream.m3u8":  "hls",
+	"/api/stream.mjpeg": "mjpeg",
+	"/api/frame.jpeg":   "mjpeg",
+	"/api/stream.ascii": "mjpeg",
+	"/api/stream.y4m":   "mjpeg",
+	"/api/stream.ts":    "mpegts",
+	"/api/stream.aac":   "mpegts",
+	"/api/stream.flv":   "rtmp",
+}
+
+func apiTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	token := &Token{
+		ID:      core.RandString(16, 62),
+		Subject: query.Get("sub"),
+		Stream:  query.Get("src"),
+		IP:      query.Get("ip"),
+	}
+
+	if token.Stream == "" {
+		http.Error(w, "src is required", http.StatusBadRequest)
+		return
+	}
+
+	if s := query.Get("formats"); s != "" {
+		token.Formats = strings.Split(s, ",")
+	}
+
+	if s := query.Get("ttl"); s != "" {
+		ttl, err := time.ParseDuration(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		token.Expires = time.Now().Add(ttl).Unix()
+	}
+
+	s, err := NewToken(token)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ResponseJSON(w, map[string]any{"token": s, "expires": token.Expires})
+}
+
+func matchIP(pattern, remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	if strings.IndexByte(pattern, '/') > 0 {
+		_, ipnet, err := net.ParseCIDR(pattern)
+		return err == nil && ipnet.Contains(ip)
+	}
+
+	return ip.Equal(net.ParseIP(pattern))
+}
+
+func isLoopback(remoteAddr string) bool {
+	if remoteAddr == "@" {
+		return true // unix socket
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
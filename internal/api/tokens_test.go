@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorize(t *testing.T) {
+	tokenSecret = []byte("secret")
+	tokenRequired = true
+	defer func() {
+		tokenSecret = nil
+		tokenRequired = false
+	}()
+
+	token, err := NewToken(&Token{
+		Stream:  "camera1",
+		Formats: []string{"rtsp", "webrtc"},
+		IP:      "192.168.1.0/24",
+		Expires: time.Now().Add(time.Minute).Unix(),
+	})
+	require.Nil(t, err)
+
+	t1, err := Authorize(token, "camera1", "rtsp", "192.168.1.123:51234")
+	require.Nil(t, err)
+	require.NotNil(t, t1)
+	require.NotEmpty(t, t1.ID)
+
+	_, err = Authorize(token, "camera2", "rtsp", "192.168.1.123:51234")
+	require.ErrorIs(t, err, ErrTokenStream)
+
+	_, err = Authorize(token, "camera1", "rtmp", "192.168.1.123:51234")
+	require.ErrorIs(t, err, ErrTokenFormat)
+
+	_, err = Authorize(token, "camera1", "rtsp", "10.0.0.1:51234")
+	require.ErrorIs(t, err, ErrTokenIP)
+
+	_, err = Authorize("", "camera1", "rtsp", "10.0.0.1:51234")
+	require.ErrorIs(t, err, ErrTokenRequired)
+
+	t1, err = Authorize("", "camera1", "rtsp", "127.0.0.1:51234")
+	require.Nil(t, err)
+	require.Nil(t, t1)
+
+	expired, err := NewToken(&Token{Stream: "camera1", Expires: time.Now().Add(-time.Minute).Unix()})
+	require.Nil(t, err)
+
+	_, err = Authorize(expired, "camera1", "rtsp", "192.168.1.123:51234")
+	require.ErrorIs(t, err, ErrTokenExpired)
+
+	_, err = NewToken(&Token{Stream: "camera1", Formats: []string{"fmp4"}})
+	require.NotNil(t, err)
+}
+
+func TestTokenFormats(t *testing.T) {
+	token := &Token{Stream: "camera1", Formats: []string{"mp4"}}
+	require.Nil(t, token.Allow("", "mp4", ""))
+	require.Nil(t, token.Allow("", "mse", ""))
+	require.ErrorIs(t, token.Allow("", "webrtc", ""), ErrTokenFormat)
+
+	token = &Token{Stream: "camera1", Formats: []string{"mse"}}
+	require.ErrorIs(t, token.Allow("", "mp4", ""), ErrTokenFormat)
+}
+
+func TestCheckRequestToken(t *testing.T) {
+	token := &Token{Stream: "camera1", Formats: []string{"mse", "hls"}}
+
+	r := httptest.NewRequest("GET", "/api/ws?src=camera1", nil)
+	require.Nil(t, checkRequestToken(token, r))
+
+	r = httptest.NewRequest("GET", "/api/stream.m3u8?src=camera1", nil)
+	require.Nil(t, checkRequestToken(token, r))
+
+	r = httptest.NewRequest("GET", "/api/hls/playlist.m3u8?id=abcd", nil)
+	require.Nil(t, checkRequestToken(token, r))
+
+	r = httptest.NewRequest("GET", "/api/stream.mp4?src=camera1", nil)
+	require.ErrorIs(t, checkRequestToken(token, r), ErrTokenFormat)
+
+	r = httptest.NewRequest("GET", "/api/ws?src=camera2", nil)
+	require.ErrorIs(t, checkRequestToken(token, r), ErrTokenStream)
+
+	r = httptest.NewRequest("POST", "/api/webrtc?dst=camera1", nil)
+	require.NotNil(t, checkRequestToken(token, r))
+
+	r = httptest.NewRequest("GET", "/api/streams", nil)
+	require.NotNil(t, checkRequestToken(token, r))
+}
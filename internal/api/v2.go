@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/pkg/shell"
+)
+
+// Route - API v2 endpoint, also used for the OpenAPI document
+type Route struct {
+	Method  string   // GET, POST, PUT, DELETE
+	Path    string   // relative to api/v2: "streams/{name}"
+	Summary string   // short description
+	Query   []string // optional query params
+	Body    string   // request body JSON example, for POST and PUT
+	Status  int      // success status, default 200
+}
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("already exists")
+)
+
+var v2Mux = http.NewServeMux()
+var v2Routes []*Route
+var v2Once sync.Once
+
+// HandleRoute - add API v2 endpoint with method and path wildcards
+func HandleRoute(route *Route, handler http.HandlerFunc) {
+	v2Once.Do(func() {
+		HandleFunc("api/v2/", handlerV2)
+		v2Routes = append(v2Routes, &Route{Method: "GET", Path: "openapi.json", Summary: "OpenAPI document"})
+		v2Mux.HandleFunc("GET "+basePath+"/api/v2/openapi.json", openapiHandler)
+	})
+
+	if route.Status == 0 {
+		route.Status = http.StatusOK
+	}
+
+	v2Routes = append(v2Routes, route)
+	v2Mux.HandleFunc(route.Method+" "+basePath+"/api/v2/"+route.Path, handler)
+}
+
+func handlerV2(w http.ResponseWriter, r *http.Request) {
+	handler, pattern := v2Mux.Handler(r)
+	if pattern == "" {
+		// convert default 404 and 405 text responses to JSON
+		ew := &errorWriter{ResponseWriter: w}
+		handler.ServeHTTP(ew, r)
+		ResponseError(w, ew.status, errors.New(strings.ToLower(http.StatusText(ew.status))))
+		return
+	}
+	v2Mux.ServeHTTP(w, r)
+}
+
+type errorWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *errorWriter) WriteHeader(statusCode int) {
+	w.status = statusCode
+}
+
+func (w *errorWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// ErrorBody - JSON body for all API v2 errors
+type ErrorBody struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+func ResponseError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", MimeJSON)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(&ErrorBody{Status: status, Error: err.Error()})
+}
+
+// ResponseStatusJSON - JSON with status code, camera passwords are hidden
+func ResponseStatusJSON(w http.ResponseWriter, status int, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		ResponseError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", MimeJSON)
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(shell.MaskCredentials(string(b))))
+}
+
+// DecodeJSON - request body for POST and PUT
+func DecodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)}`)
+
+func openapiHandler(w http.ResponseWriter, r *http.Request) {
+	paths := map[string]map[string]any{}
+
+	for _, route := range v2Routes {
+		path := "/" + route.Path
+
+		var params []any
+		for _, m := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			params = append(params, map[string]any{
+				"name": m[1], "in": "path", "required": true, "schema": map[string]string{"type": "string"},
+			})
+		}
+		for _, name := range route.Query {
+			params = append(params, map[string]any{
+				"name": name, "in": "query", "schema": map[string]string{"type": "string"},
+			})
+		}
+
+		tag, _, _ := strings.Cut(route.Path, "/")
+
+		op := map[string]any{
+			"summary": route.Summary,
+			"tags":    []string{tag},
+			"responses": map[string]any{
+				strconv.Itoa(route.Status): map[string]any{"description": http.StatusText(route.Status)},
+				"default": map[string]any{
+					"description": "Error",
+					"content": map[string]any{
+						MimeJSON: map[string]any{"schema": map[string]string{"$ref": "#/components/schemas/Error"}},
+					},
+				},
+			},
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if route.Body != "" {
+			var example any
+			_ = json.Unmarshal([]byte(route.Body), &example)
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{MimeJSON: map[string]any{"example": example}},
+			}
+		}
+
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	ResponsePrettyJSON(w, map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]string{"title": "go2rtc", "version": app.Version},
+		"servers": []any{map[string]string{"url": basePath + "/api/v2"}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": map[string]any{
+				"Error": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"status": map[string]string{"type": "integer"},
+						"error":  map[string]string{"type": "string"},
+					},
+				},
+			},
+		},
+	})
+}
@@ -106,6 +106,9 @@ func apiWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	token := api.RequestToken(r)
+	role := api.RequestRole(r)
+
 	tr := &Transport{Request: r}
 	tr.OnWrite(func(msg any) error {
 		_ = ws.SetWriteDeadline(time.Now().Add(time.Second * 5))
@@ -129,6 +132,20 @@ func apiWS(w http.ResponseWriter, r *http.Request) {
 
 		log.Trace().Str("type", msg.Type).Msg("[api] ws msg")
 
+		if token != nil {
+			// message type prefix is output format: mse, webrtc/offer...
+			format, _, _ := strings.Cut(msg.Type, "/")
+			if err = token.Allow("", format, r.RemoteAddr); err != nil {
+				tr.Write(&Message{Type: "error", Value: msg.Type + ": " + err.Error()})
+				continue
+			}
+		}
+
+		if role < api.MessageRole(msg.Type) {
+			tr.Write(&Message{Type: "error", Value: msg.Type + ": forbidden"})
+			continue
+		}
+
 		if handler := wsHandlers[msg.Type]; handler != nil {
 			go func() {
 				if err = handler(tr, msg); err != nil {
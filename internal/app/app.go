@@ -7,6 +7,8 @@ import (
 	"os/exec"
 	"runtime"
 	"runtime/debug"
+
+	"github.com/AlexxIT/go2rtc/pkg/shell"
 )
 
 var (
@@ -20,18 +22,22 @@ const usage = `Usage of go2rtc:
 
   -c, --config   Path to config file or config string as YAML or JSON, support multiple
   -d, --daemon   Run in background
+  -e, --encrypt  Encrypt value with GO2RTC_MASTER_KEY for ${enc:...} config templates and exit
   -v, --version  Print version and exit
 `
 
 func Init() {
 	var config flagConfig
 	var daemon bool
+	var encrypt string
 	var version bool
 
 	flag.Var(&config, "config", "")
 	flag.Var(&config, "c", "")
 	flag.BoolVar(&daemon, "daemon", false, "")
 	flag.BoolVar(&daemon, "d", false, "")
+	flag.StringVar(&encrypt, "encrypt", "", "")
+	flag.StringVar(&encrypt, "e", "", "")
 	flag.BoolVar(&version, "version", false, "")
 	flag.BoolVar(&version, "v", false, "")
 
@@ -45,6 +51,16 @@ func Init() {
 		os.Exit(0)
 	}
 
+	if encrypt != "" {
+		value, err := shell.Encrypt(os.Getenv(shell.MasterKeyEnv), encrypt)
+		if err != nil {
+			fmt.Println("Failed to encrypt:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("${enc:%s}\n", value)
+		os.Exit(0)
+	}
+
 	if daemon && os.Getppid() != 1 {
 		if runtime.GOOS == "windows" {
 			fmt.Println("Daemon mode is not supported on Windows")
@@ -68,6 +84,8 @@ func Init() {
 
 	initConfig(config)
 	initLogger()
+	initShutdown()
+	initState()
 
 	platform := fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH)
 	Logger.Info().Str("version", Version).Str("platform", platform).Str("revision", revision).Msg("go2rtc")
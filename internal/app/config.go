@@ -20,6 +20,9 @@ func LoadConfig(v any) {
 	for _, data := range configs {
 		if err := yaml.Unmarshal(data, v); err != nil {
 			Logger.Warn().Err(err).Send()
+			if configErr == nil {
+				configErr = err
+			}
 		}
 	}
 }
@@ -73,7 +76,12 @@ func initConfig(confs flagConfig) {
 				ConfigPath = conf
 			}
 
-			if data, _ = os.ReadFile(conf); data == nil {
+			data, err := os.ReadFile(conf)
+			if err != nil {
+				// missing config is OK, default settings will be used
+				if !os.IsNotExist(err) {
+					configErr = err
+				}
 				continue
 			}
 
@@ -128,6 +136,17 @@ func ListenConfig() {
 	}
 	LoadConfig(&cfg)
 	url, _ := cfg.RabbitMQ["url"].(string)
+	if url == "" {
+		return
+	}
+
+	var connected atomic.Bool
+	AddReadyCheck("amqp", func() error {
+		if !connected.Load() {
+			return errors.New("amqp: not connected")
+		}
+		return nil
+	})
 
 	conn, err := amqp.Dial(url)
 	if err != nil {
@@ -154,6 +173,9 @@ func ListenConfig() {
 		return
 	}
 
+	connected.Store(true)
+	defer connected.Store(false)
+
 	const (
 		ADD    string = "add"
 		REMOVE string = "remove"
@@ -170,7 +192,7 @@ func ListenConfig() {
 					restartCountdown.Add(-1)
 					if restartCountdown.Load() == 0 {
 						log.Info().Msg("no more messages, restarting...")
-						shell.Restart()
+						Restart()
 					}
 				}
 			}()
@@ -209,35 +231,18 @@ func ListenConfig() {
 			continue
 		}
 
-		var updatedConfigBytes []byte
+		// stream with device_name, so record module will start recording after restart
 		switch action {
 		case ADD:
 			delete(msg, "guid")
-			changes := map[string]map[string]map[string]string{
-				"streams": {
-					guid: msg,
-				},
-			}
-			bytes, err := yaml.Encode(changes, 2)
-			if err != nil {
-				log.Error().Err(err).Msgf("failed to encode yaml:\n%v\n", changes)
-				continue
-			}
-			updatedConfigBytes, err = MergeYAML(ConfigPath, bytes)
-			if err != nil {
-				log.Error().Err(err).Msgf("failed to merge yaml:\n%s\n", bytes)
-				continue
-			}
+			err = PatchState(guid, msg, "streams")
 		case REMOVE:
-			configBytes, _ := os.ReadFile(ConfigPath)
-			var config map[string]map[string]any
-			_ = yaml.Unmarshal(configBytes, &config)
-			delete(config["streams"], guid)
-			updatedConfigBytes, _ = yaml.Encode(config, 2)
+			err = PatchState(guid, nil, "streams")
 		default:
+			continue
 		}
-		if err = os.WriteFile(ConfigPath, updatedConfigBytes, 0644); err != nil {
-			log.Error().Err(err).Msg("failed to save config")
+		if err != nil {
+			log.Error().Err(err).Msg("failed to save state")
 			return
 		}
 	}
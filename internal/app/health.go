@@ -0,0 +1,67 @@
+package app
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// checks - modules state for api/health (process can work) and api/ready (process can serve)
+var healthChecks = map[string]func() error{}
+var readyChecks = map[string]func() error{}
+var checksMu sync.Mutex
+
+var initialized atomic.Bool
+var configErr error
+
+var (
+	ErrNotInitialized = errors.New("modules not initialized")
+	ErrShuttingDown   = errors.New("shutting down")
+)
+
+// AddHealthCheck - module is healthy while check returns nil
+func AddHealthCheck(name string, check func() error) {
+	checksMu.Lock()
+	healthChecks[name] = check
+	checksMu.Unlock()
+}
+
+// AddReadyCheck - module is ready while check returns nil
+func AddReadyCheck(name string, check func() error) {
+	checksMu.Lock()
+	readyChecks[name] = check
+	checksMu.Unlock()
+}
+
+// HealthChecks - results of all health checks, nil for healthy
+func HealthChecks() map[string]error {
+	checksMu.Lock()
+	defer checksMu.Unlock()
+
+	results := map[string]error{"config": configErr}
+	for name, check := range healthChecks {
+		results[name] = check()
+	}
+	return results
+}
+
+// ReadyChecks - results of health and ready checks, nil for ready
+func ReadyChecks() map[string]error {
+	results := HealthChecks()
+
+	if !initialized.Load() {
+		results["modules"] = ErrNotInitialized
+	} else if ShuttingDown() {
+		results["modules"] = ErrShuttingDown
+	} else {
+		results["modules"] = nil
+	}
+
+	checksMu.Lock()
+	defer checksMu.Unlock()
+
+	for name, check := range readyChecks {
+		results[name] = check()
+	}
+	return results
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 
+	"github.com/AlexxIT/go2rtc/pkg/shell"
 	"github.com/mattn/go-isatty"
 	"github.com/rs/zerolog"
 )
@@ -80,6 +81,9 @@ func initLogger() {
 		writer = MemoryLog
 	}
 
+	// hide camera passwords and resolved secrets from logs
+	writer = &maskWriter{w: writer}
+
 	lvl, _ := zerolog.ParseLevel(modules["level"])
 	Logger = zerolog.New(writer).Level(lvl)
 
@@ -99,6 +103,17 @@ var modules = map[string]string{
 	"time":   zerolog.TimeFormatUnixMs,
 }
 
+type maskWriter struct {
+	w io.Writer
+}
+
+func (m *maskWriter) Write(p []byte) (n int, err error) {
+	if _, err = m.w.Write([]byte(shell.MaskCredentials(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
 const chunkSize = 1 << 16
 
 type circularBuffer struct {
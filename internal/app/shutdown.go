@@ -0,0 +1,109 @@
+package app
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/shell"
+)
+
+type shutdownHook struct {
+	name string
+	hook func()
+}
+
+var (
+	shutdownHooks   []shutdownHook
+	shutdownMu      sync.Mutex
+	shutdownTimeout = 10 * time.Second
+	shuttingDown    atomic.Bool
+)
+
+func initShutdown() {
+	var cfg struct {
+		Mod struct {
+			Timeout time.Duration `yaml:"timeout"`
+		} `yaml:"shutdown"`
+	}
+
+	cfg.Mod.Timeout = shutdownTimeout
+
+	LoadConfig(&cfg)
+
+	shutdownTimeout = cfg.Mod.Timeout
+}
+
+// OnShutdown - add module stop hook, hooks are called in reverse order (like defer),
+// so output modules stop before the streams module
+func OnShutdown(name string, hook func()) {
+	shutdownMu.Lock()
+	shutdownHooks = append(shutdownHooks, shutdownHook{name: name, hook: hook})
+	shutdownMu.Unlock()
+}
+
+// ShuttingDown - modules should not accept new connections after shutdown started
+func ShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// Shutdown - call all stop hooks with timeout and exit the process
+func Shutdown(code int) {
+	stop()
+	os.Exit(code)
+}
+
+// Restart - call all stop hooks with timeout and replace the process
+func Restart() {
+	stop()
+	shell.Restart()
+}
+
+// RunUntilSignal - wait SIGINT or SIGTERM and shutdown, second signal exits immediately
+func RunUntilSignal() {
+	// all modules called from main before this function
+	initialized.Store(true)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	Logger.Info().Msgf("shutdown with signal: %s", <-sigs)
+
+	go func() {
+		Logger.Warn().Msgf("exit with signal: %s", <-sigs)
+		os.Exit(1)
+	}()
+
+	Shutdown(0)
+}
+
+func stop() {
+	// only first call runs hooks, other calls wait for the process exit
+	if !shuttingDown.CompareAndSwap(false, true) {
+		select {}
+	}
+
+	shutdownMu.Lock()
+	hooks := shutdownHooks
+	shutdownMu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		for i := len(hooks) - 1; i >= 0; i-- {
+			Logger.Debug().Msgf("shutdown %s", hooks[i].name)
+			hooks[i].hook()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		Logger.Info().Msg("shutdown complete")
+	case <-time.After(shutdownTimeout):
+		Logger.Warn().Msgf("shutdown timeout %s", shutdownTimeout)
+	}
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/AlexxIT/go2rtc/pkg/yaml"
+)
+
+// state - runtime changes (streams from API, MQTT, AMQP, publish targets, recording toggles)
+// in a separate file, so the user config keeps its formatting and comments
+var state map[string]any
+var statePath string
+var stateMu sync.Mutex
+
+func initState() {
+	var cfg struct {
+		Mod struct {
+			Path string `yaml:"path"`
+		} `yaml:"state"`
+	}
+
+	// go2rtc.yaml => go2rtc.state.yaml
+	if ConfigPath != "" {
+		cfg.Mod.Path = strings.TrimSuffix(ConfigPath, filepath.Ext(ConfigPath)) + ".state.yaml"
+	}
+
+	LoadConfig(&cfg)
+
+	if cfg.Mod.Path == "" {
+		return
+	}
+
+	statePath = cfg.Mod.Path
+	Info["state_path"] = statePath
+
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			Logger.Warn().Err(err).Send()
+		}
+		state = map[string]any{}
+		return
+	}
+
+	if err = yaml.Unmarshal(data, &state); err != nil {
+		Logger.Warn().Err(err).Msgf("state: %s", statePath)
+	}
+	if state == nil {
+		state = map[string]any{}
+	}
+
+	// state is loaded on top of the user configs
+	configs = append(configs, data)
+}
+
+// PatchState - save runtime change, nil value overrides the key from the user config
+func PatchState(key string, value any, path ...string) error {
+	if statePath == "" {
+		return errors.New("state file disabled")
+	}
+
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	node := state
+	for _, name := range path {
+		child, ok := node[name].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			node[name] = child
+		}
+		node = child
+	}
+	node[key] = value
+
+	b, err := yaml.Encode(state, 2)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(statePath, b)
+}
+
+// writeFileAtomic - readers never see half written file
+func writeFileAtomic(name string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
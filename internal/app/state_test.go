@@ -0,0 +1,36 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlexxIT/go2rtc/pkg/yaml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchState(t *testing.T) {
+	statePath = filepath.Join(t.TempDir(), "go2rtc.state.yaml")
+	state = map[string]any{}
+	defer func() { statePath = "" }()
+
+	require.Nil(t, PatchState("camera1", "rtsp://192.168.1.123/stream", "streams"))
+	require.Nil(t, PatchState("camera2", nil, "streams"))
+	require.Nil(t, PatchState("camera1", "Front door", "record", "streams"))
+
+	data, err := os.ReadFile(statePath)
+	require.Nil(t, err)
+
+	// state overrides the user config
+	var cfg struct {
+		Streams map[string]any `yaml:"streams"`
+		Record  struct {
+			Streams map[string]any `yaml:"streams"`
+		} `yaml:"record"`
+	}
+	require.Nil(t, yaml.Unmarshal([]byte("streams: {camera2: rtsp://192.168.1.124/stream}"), &cfg))
+	require.Nil(t, yaml.Unmarshal(data, &cfg))
+
+	require.Equal(t, map[string]any{"camera1": "rtsp://192.168.1.123/stream", "camera2": nil}, cfg.Streams)
+	require.Equal(t, "Front door", cfg.Record.Streams["camera1"])
+}
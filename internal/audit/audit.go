@@ -0,0 +1,257 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/rs/zerolog"
+)
+
+func Init() {
+	var cfg struct {
+		Mod struct {
+			Path     string `yaml:"path"`
+			MaxSize  int    `yaml:"max_size"`  // MB
+			MaxFiles int    `yaml:"max_files"` // rotated files
+		} `yaml:"audit"`
+	}
+
+	cfg.Mod.MaxSize = 10
+	cfg.Mod.MaxFiles = 5
+
+	app.LoadConfig(&cfg)
+
+	if cfg.Mod.Path == "" {
+		return
+	}
+
+	log = app.GetLogger("audit")
+
+	auditLog = &logFile{
+		path:     cfg.Mod.Path,
+		maxSize:  int64(cfg.Mod.MaxSize) << 20,
+		maxFiles: cfg.Mod.MaxFiles,
+	}
+
+	streams.OnEvent(handleEvent)
+
+	api.HandleFunc("api/audit", apiAudit)
+}
+
+var log zerolog.Logger
+var auditLog *logFile
+
+// Record - one line of the audit log
+type Record struct {
+	Time       time.Time     `json:"time"`
+	Event      streams.Event `json:"event"` // consumer_connect, consumer_disconnect
+	Stream     string        `json:"stream"`
+	ID         uint32        `json:"id,omitempty"`
+	FormatName string        `json:"format_name,omitempty"`
+	Protocol   string        `json:"protocol,omitempty"`
+	RemoteAddr string        `json:"remote_addr,omitempty"`
+	UserAgent  string        `json:"user_agent,omitempty"`
+	User       string        `json:"user,omitempty"`
+	Token      string        `json:"token,omitempty"`
+	Duration   float64       `json:"duration,omitempty"` // seconds
+	Error      string        `json:"error,omitempty"`
+}
+
+// connected - credentials from connect events, session can be removed before disconnect
+var connected = map[uint32]*Record{}
+var connectedMu sync.Mutex
+
+func handleEvent(msg *streams.Message) {
+	if msg.Event != streams.EventConsumerConnect && msg.Event != streams.EventConsumerDisconnect {
+		return
+	}
+
+	rec := &Record{
+		Time:       msg.Time,
+		Event:      msg.Event,
+		Stream:     msg.Stream,
+		ID:         msg.ID,
+		FormatName: msg.FormatName,
+		Protocol:   msg.Protocol,
+		RemoteAddr: msg.RemoteAddr,
+		UserAgent:  msg.UserAgent,
+		User:       msg.User,
+		Token:      msg.Token,
+		Duration:   msg.Duration,
+		Error:      msg.Error,
+	}
+
+	if rec.ID != 0 {
+		connectedMu.Lock()
+		if rec.Event == streams.EventConsumerConnect {
+			connected[rec.ID] = rec
+		} else if prev := connected[rec.ID]; prev != nil {
+			delete(connected, rec.ID)
+			if rec.User == "" {
+				rec.User = prev.User
+			}
+			if rec.Token == "" {
+				rec.Token = prev.Token
+			}
+		}
+		connectedMu.Unlock()
+	}
+
+	if err := auditLog.write(rec); err != nil {
+		log.Error().Err(err).Caller().Send()
+	}
+}
+
+// logFile - append only JSON lines file with rotation by size: audit.log, audit.log.1, audit.log.2...
+type logFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	file *os.File
+	size int64
+	mu   sync.Mutex
+}
+
+func (l *logFile) write(rec *Record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil && l.maxSize > 0 && l.size+int64(len(b)) > l.maxSize {
+		if err = l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if l.file == nil {
+		if l.file, err = os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600); err != nil {
+			return err
+		}
+		info, err := l.file.Stat()
+		if err != nil {
+			return err
+		}
+		l.size = info.Size()
+	}
+
+	n, err := l.file.Write(b)
+	l.size += int64(n)
+	return err
+}
+
+func (l *logFile) rotate() error {
+	_ = l.file.Close()
+	l.file = nil
+
+	if l.maxFiles <= 0 {
+		return os.Remove(l.path)
+	}
+
+	_ = os.Remove(l.name(l.maxFiles))
+	for i := l.maxFiles - 1; i > 0; i-- {
+		_ = os.Rename(l.name(i), l.name(i+1))
+	}
+	return os.Rename(l.path, l.name(1))
+}
+
+func (l *logFile) name(i int) string {
+	if i == 0 {
+		return l.path
+	}
+	return l.path + "." + strconv.Itoa(i)
+}
+
+// read - records from the oldest to the newest file
+func (l *logFile) read(filter func(rec *Record) bool) ([]*Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := []*Record{}
+
+	for i := l.maxFiles; i >= 0; i-- {
+		f, err := os.Open(l.name(i))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			rec := &Record{}
+			if err = json.Unmarshal(scanner.Bytes(), rec); err != nil {
+				continue
+			}
+			if filter(rec) {
+				records = append(records, rec)
+			}
+		}
+
+		_ = f.Close()
+	}
+
+	return records, nil
+}
+
+func apiAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	src := query.Get("src")
+
+	var from, to time.Time
+	var err error
+
+	if s := query.Get("from"); s != "" {
+		if from, err = parseTime(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if s := query.Get("to"); s != "" {
+		if to, err = parseTime(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	records, err := auditLog.read(func(rec *Record) bool {
+		if src != "" && rec.Stream != src {
+			return false
+		}
+		if !from.IsZero() && rec.Time.Before(from) {
+			return false
+		}
+		if !to.IsZero() && rec.Time.After(to) {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	api.ResponseJSON(w, records)
+}
+
+// parseTime - RFC3339 or unix seconds
+func parseTime(s string) (time.Time, error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(i, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
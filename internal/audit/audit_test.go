@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	// small size for rotation after each record
+	auditLog = &logFile{path: path, maxSize: 100, maxFiles: 2}
+
+	now := time.Now()
+
+	handleEvent(&streams.Message{
+		Event: streams.EventConsumerConnect, Stream: "camera1", Time: now.Add(-time.Hour), ID: 1, User: "admin",
+	})
+	handleEvent(&streams.Message{
+		Event: streams.EventConsumerDisconnect, Stream: "camera1", Time: now, ID: 1, Duration: 3600,
+	})
+	handleEvent(&streams.Message{
+		Event: streams.EventConsumerConnect, Stream: "camera2", Time: now, ID: 2, Token: "abcd",
+	})
+	handleEvent(&streams.Message{Event: streams.EventProducerOnline, Stream: "camera1"})
+
+	_, err := os.Stat(path + ".2")
+	require.Nil(t, err)
+
+	records, err := auditLog.read(func(rec *Record) bool { return rec.Stream == "camera1" })
+	require.Nil(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, streams.EventConsumerConnect, records[0].Event)
+	require.Equal(t, "admin", records[1].User) // from connect event
+	require.Equal(t, 3600.0, records[1].Duration)
+
+	records, err = auditLog.read(func(rec *Record) bool { return !rec.Time.Before(now) })
+	require.Nil(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, "abcd", records[1].Token)
+}
@@ -74,10 +74,11 @@ func handlerStream(w http.ResponseWriter, r *http.Request) {
 
 	if err := stream.AddConsumer(cons); err != nil {
 		log.Error().Err(err).Caller().Send()
+		http.Error(w, err.Error(), streams.HTTPStatus(err))
 		return
 	}
 
-	session := NewSession(cons)
+	session := NewSession(cons, r.URL.Query().Get("token"))
 	session.alive = time.AfterFunc(keepalive, func() {
 		sessionsMu.Lock()
 		delete(sessions, session.id)
@@ -90,7 +91,10 @@ func handlerStream(w http.ResponseWriter, r *http.Request) {
 	sessions[session.id] = session
 	sessionsMu.Unlock()
 
-	go session.Run()
+	go func() {
+		session.Run()
+		session.close(stream.CloseReason(cons))
+	}()
 
 	if _, err := w.Write(session.Main()); err != nil {
 		log.Error().Err(err).Caller().Send()
@@ -115,6 +119,11 @@ func handlerPlaylist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := session.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+
 	if _, err := w.Write(session.Playlist()); err != nil {
 		log.Error().Err(err).Caller().Send()
 	}
@@ -138,6 +147,11 @@ func handlerSegmentTS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := session.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+
 	session.alive.Reset(keepalive)
 
 	data := session.Segment()
@@ -170,6 +184,11 @@ func handlerInit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := session.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+
 	data := session.Init()
 	if data == nil {
 		log.Warn().Msgf("[hls] can't get init %s", r.URL.RawQuery)
@@ -202,6 +221,11 @@ func handlerSegmentMP4(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := session.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+
 	session.alive.Reset(keepalive)
 
 	data := session.Segment()
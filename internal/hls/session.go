@@ -3,6 +3,7 @@ package hls
 import (
 	"fmt"
 	"io"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -14,40 +15,48 @@ import (
 type Session struct {
 	cons     core.Consumer
 	id       string
+	query    string // for playlist URLs
 	template string
 	init     []byte
 	buffer   []byte
 	seq      int
 	alive    *time.Timer
+	err      error // why session was closed by session limits
 	mu       sync.Mutex
 }
 
-func NewSession(cons core.Consumer) *Session {
+// NewSession - token will be added to the playlist URLs if the stream was opened with access token
+func NewSession(cons core.Consumer, token string) *Session {
 	s := &Session{
 		id:   core.RandString(8, 62),
 		cons: cons,
 	}
 
+	s.query = "id=" + s.id
+	if token != "" {
+		s.query += "&token=" + url.QueryEscape(token)
+	}
+
 	// two segments important for Chromecast
 	if _, ok := cons.(*mp4.Consumer); ok {
 		s.template = `#EXTM3U
 #EXT-X-VERSION:6
 #EXT-X-TARGETDURATION:1
 #EXT-X-MEDIA-SEQUENCE:%d
-#EXT-X-MAP:URI="init.mp4?id=` + s.id + `"
+#EXT-X-MAP:URI="init.mp4?` + s.query + `"
 #EXTINF:0.500,
-segment.m4s?id=` + s.id + `&n=%d
+segment.m4s?` + s.query + `&n=%d
 #EXTINF:0.500,
-segment.m4s?id=` + s.id + `&n=%d`
+segment.m4s?` + s.query + `&n=%d`
 	} else {
 		s.template = `#EXTM3U
 #EXT-X-VERSION:3
 #EXT-X-TARGETDURATION:1
 #EXT-X-MEDIA-SEQUENCE:%d
 #EXTINF:0.500,
-segment.ts?id=` + s.id + `&n=%d
+segment.ts?` + s.query + `&n=%d
 #EXTINF:0.500,
-segment.ts?id=` + s.id + `&n=%d`
+segment.ts?` + s.query + `&n=%d`
 	}
 
 	return s
@@ -68,6 +77,18 @@ func (s *Session) Run() {
 	_, _ = s.cons.(io.WriterTo).WriteTo(s)
 }
 
+func (s *Session) close(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+func (s *Session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
 func (s *Session) Main() []byte {
 	type withCodecs interface {
 		Codecs() []*core.Codec
@@ -79,7 +100,7 @@ func (s *Session) Main() []byte {
 	// bandwidth important for Safari, codecs useful for smooth playback
 	return []byte(`#EXTM3U
 #EXT-X-STREAM-INF:BANDWIDTH=192000,CODECS="` + codecs + `"
-hls/playlist.m3u8?id=` + s.id)
+hls/playlist.m3u8?` + s.query)
 }
 
 func (s *Session) Playlist() []byte {
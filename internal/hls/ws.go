@@ -29,7 +29,7 @@ func handlerWSHLS(tr *ws.Transport, msg *ws.Message) error {
 		return err
 	}
 
-	session := NewSession(cons)
+	session := NewSession(cons, tr.Request.URL.Query().Get("token"))
 
 	session.alive = time.AfterFunc(keepalive, func() {
 		sessionsMu.Lock()
@@ -43,7 +43,10 @@ func handlerWSHLS(tr *ws.Transport, msg *ws.Message) error {
 	sessions[session.id] = session
 	sessionsMu.Unlock()
 
-	go session.Run()
+	go func() {
+		session.Run()
+		session.close(stream.CloseReason(cons))
+	}()
 
 	main := session.Main()
 	tr.Write(&ws.Message{Type: "hls", Value: string(main)})
@@ -0,0 +1,292 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/record"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/shell"
+)
+
+func Init() {
+	api.HandleFunc("api/metrics", apiMetrics)
+}
+
+// Prometheus text format
+// https://prometheus.io/docs/instrumenting/exposition_formats/
+const mimeMetrics = "text/plain; version=0.0.4; charset=utf-8"
+
+var startTime = time.Now()
+
+func apiMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+
+	m := &writer{}
+	writeStreams(m)
+	writeRecorders(m)
+	writeProcess(m)
+
+	api.Response(w, m.Bytes(), mimeMetrics)
+}
+
+func writeStreams(m *writer) {
+	names := streams.GetAll()
+	sort.Strings(names)
+
+	all := make([]streams.StreamStats, len(names))
+	for i, name := range names {
+		if stream := streams.Get(name); stream != nil {
+			all[i] = stream.Stats()
+		}
+	}
+
+	m.Help("go2rtc_stream_online", "gauge", "Stream has at least one running producer")
+	for i, name := range names {
+		var online float64
+		for _, prod := range all[i].Producers {
+			if prod.State == "start" || prod.State == "external" || prod.State == "internal" {
+				online = 1
+				break
+			}
+		}
+		m.Value("go2rtc_stream_online", online, "stream", name)
+	}
+
+	m.Help("go2rtc_stream_bitrate_bps", "gauge", "Incoming bitrate since previous scrape")
+	for i, name := range names {
+		m.Value("go2rtc_stream_bitrate_bps", bitrate(name, all[i]), "stream", name)
+	}
+	pruneSamples(names)
+
+	m.Help("go2rtc_producer_state", "gauge", "Producer state: none, medias, tracks, start, external, internal")
+	for i, name := range names {
+		for _, prod := range all[i].Producers {
+			m.Value("go2rtc_producer_state", 1, "stream", name, "source", source(prod.URL), "format", prod.FormatName, "state", prod.State)
+		}
+	}
+
+	m.Help("go2rtc_producer_reconnects_total", "counter", "Producer reconnect attempts")
+	for i, name := range names {
+		for _, prod := range all[i].Producers {
+			m.Value("go2rtc_producer_reconnects_total", float64(prod.Reconnects), "stream", name, "source", source(prod.URL))
+		}
+	}
+
+	m.Help("go2rtc_producer_received_bytes_total", "counter", "Producer payload bytes")
+	for i, name := range names {
+		for _, prod := range all[i].Producers {
+			m.Value("go2rtc_producer_received_bytes_total", float64(prod.Bytes), "stream", name, "source", source(prod.URL), "format", prod.FormatName)
+		}
+	}
+
+	m.Help("go2rtc_producer_received_packets_total", "counter", "Producer packets")
+	for i, name := range names {
+		for _, prod := range all[i].Producers {
+			m.Value("go2rtc_producer_received_packets_total", float64(prod.Packets), "stream", name, "source", source(prod.URL), "format", prod.FormatName)
+		}
+	}
+
+	// consumers are grouped by format, because consumer ID is useless as a label
+	groups := make([][]consumerGroup, len(names))
+	for i := range names {
+		groups[i] = groupConsumers(all[i].Consumers, all[i].Closed)
+	}
+
+	m.Help("go2rtc_stream_consumers", "gauge", "Active consumers")
+	for i, name := range names {
+		for _, g := range groups[i] {
+			m.Value("go2rtc_stream_consumers", float64(g.Count), "stream", name, "format", g.FormatName)
+		}
+	}
+
+	m.Help("go2rtc_consumer_sent_bytes_total", "counter", "Payload bytes sent to consumers")
+	for i, name := range names {
+		for _, g := range groups[i] {
+			m.Value("go2rtc_consumer_sent_bytes_total", float64(g.Bytes), "stream", name, "format", g.FormatName)
+		}
+	}
+
+	m.Help("go2rtc_consumer_sent_packets_total", "counter", "Packets sent to consumers")
+	for i, name := range names {
+		for _, g := range groups[i] {
+			m.Value("go2rtc_consumer_sent_packets_total", float64(g.Packets), "stream", name, "format", g.FormatName)
+		}
+	}
+
+	m.Help("go2rtc_consumer_dropped_packets_total", "counter", "Packets dropped for slow consumers")
+	for i, name := range names {
+		for _, g := range groups[i] {
+			m.Value("go2rtc_consumer_dropped_packets_total", float64(g.Drops), "stream", name, "format", g.FormatName)
+		}
+	}
+}
+
+type consumerGroup struct {
+	streams.ConsumerStats
+	Count int
+}
+
+// groupConsumers - active consumers are counted, closed consumers only add
+// their totals, so counters don't go down on disconnect
+func groupConsumers(active, closed []streams.ConsumerStats) (groups []consumerGroup) {
+	index := map[string]int{}
+	add := func(cons streams.ConsumerStats) *consumerGroup {
+		i, ok := index[cons.FormatName]
+		if !ok {
+			i = len(groups)
+			index[cons.FormatName] = i
+			groups = append(groups, consumerGroup{ConsumerStats: streams.ConsumerStats{FormatName: cons.FormatName}})
+		}
+		groups[i].Bytes += cons.Bytes
+		groups[i].Packets += cons.Packets
+		groups[i].Drops += cons.Drops
+		return &groups[i]
+	}
+	for _, cons := range active {
+		add(cons).Count++
+	}
+	for _, cons := range closed {
+		add(cons)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].FormatName < groups[j].FormatName
+	})
+	return
+}
+
+func writeRecorders(m *writer) {
+	written := record.BytesWritten()
+
+	names := make([]string, 0, len(written))
+	for name := range written {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	m.Help("go2rtc_recorder_written_bytes_total", "counter", "Bytes written by active recordings")
+	for _, name := range names {
+		m.Value("go2rtc_recorder_written_bytes_total", float64(written[name]), "stream", name)
+	}
+}
+
+func writeProcess(m *writer) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	m.Help("go2rtc_info", "gauge", "Application version")
+	m.Value("go2rtc_info", 1, "version", app.Version, "go_version", runtime.Version())
+
+	m.Help("process_start_time_seconds", "gauge", "Start time of the process since unix epoch in seconds")
+	m.Value("process_start_time_seconds", float64(startTime.Unix()))
+
+	m.Help("go_goroutines", "gauge", "Number of goroutines that currently exist")
+	m.Value("go_goroutines", float64(runtime.NumGoroutine()))
+
+	m.Help("go_memstats_alloc_bytes", "gauge", "Number of bytes allocated and still in use")
+	m.Value("go_memstats_alloc_bytes", float64(mem.Alloc))
+
+	m.Help("go_memstats_sys_bytes", "gauge", "Number of bytes obtained from system")
+	m.Value("go_memstats_sys_bytes", float64(mem.Sys))
+
+	m.Help("go_memstats_heap_inuse_bytes", "gauge", "Number of heap bytes that are in use")
+	m.Value("go_memstats_heap_inuse_bytes", float64(mem.HeapInuse))
+
+	m.Help("go_gc_cycles_total", "counter", "Number of completed GC cycles")
+	m.Value("go_gc_cycles_total", float64(mem.NumGC))
+}
+
+// source - hide passwords from metrics labels
+func source(url string) string {
+	return shell.MaskCredentials(url)
+}
+
+type sample struct {
+	bytes int
+	ts    time.Time
+}
+
+var samples = map[string]sample{}
+var samplesMu sync.Mutex
+
+// bitrate - calculated from producers bytes between two scrapes
+func bitrate(name string, stats streams.StreamStats) float64 {
+	var total int
+	for _, prod := range stats.Producers {
+		total += prod.Bytes
+	}
+
+	now := time.Now()
+
+	samplesMu.Lock()
+	prev, ok := samples[name]
+	samples[name] = sample{bytes: total, ts: now}
+	samplesMu.Unlock()
+
+	// counters were reset after reconnect
+	if !ok || total < prev.bytes {
+		return 0
+	}
+
+	if d := now.Sub(prev.ts).Seconds(); d > 0 {
+		return float64(total-prev.bytes) * 8 / d
+	}
+	return 0
+}
+
+// pruneSamples - forget deleted streams
+func pruneSamples(names []string) {
+	samplesMu.Lock()
+	defer samplesMu.Unlock()
+
+	for name := range samples {
+		i := sort.SearchStrings(names, name)
+		if i == len(names) || names[i] != name {
+			delete(samples, name)
+		}
+	}
+}
+
+type writer struct {
+	bytes.Buffer
+}
+
+func (w *writer) Help(name, typ, help string) {
+	w.WriteString("# HELP " + name + " " + help + "\n")
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// Value - write metric with labels as key, value pairs
+func (w *writer) Value(name string, value float64, labels ...string) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(labels[i] + `="` + escape(labels[i+1]) + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	w.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	m := &writer{}
+	m.Help("test_total", "counter", "Test counter")
+	m.Value("test_total", 12, "stream", `cam"1`, "format", "rtsp")
+	m.Value("test_total", 0.5)
+
+	require.Equal(t, `# HELP test_total Test counter
+# TYPE test_total counter
+test_total{stream="cam\"1",format="rtsp"} 12
+test_total 0.5
+`, m.String())
+}
+
+func TestGroupConsumers(t *testing.T) {
+	groups := groupConsumers([]streams.ConsumerStats{
+		{FormatName: "webrtc", Bytes: 100, Drops: 1},
+		{FormatName: "mp4", Bytes: 10},
+		{FormatName: "webrtc", Bytes: 200, Packets: 3},
+	}, []streams.ConsumerStats{
+		{FormatName: "webrtc", Bytes: 1000, Packets: 10},
+		{FormatName: "rtsp", Bytes: 50, Drops: 2},
+	})
+
+	require.Len(t, groups, 3)
+	require.Equal(t, "mp4", groups[0].FormatName)
+	require.Equal(t, 1, groups[0].Count)
+	require.Equal(t, "rtsp", groups[1].FormatName)
+	require.Equal(t, 0, groups[1].Count)
+	require.Equal(t, 50, groups[1].Bytes)
+	require.Equal(t, 2, groups[1].Drops)
+	require.Equal(t, "webrtc", groups[2].FormatName)
+	require.Equal(t, 2, groups[2].Count)
+	require.Equal(t, 1300, groups[2].Bytes)
+	require.Equal(t, 13, groups[2].Packets)
+	require.Equal(t, 1, groups[2].Drops)
+}
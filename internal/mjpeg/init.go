@@ -48,6 +48,7 @@ func handlerKeyframe(w http.ResponseWriter, r *http.Request) {
 
 	if err := stream.AddConsumer(cons); err != nil {
 		log.Error().Err(err).Caller().Send()
+		http.Error(w, err.Error(), streams.HTTPStatus(err))
 		return
 	}
 
@@ -103,6 +104,7 @@ func outputMjpeg(w http.ResponseWriter, r *http.Request) {
 
 	if err := stream.AddConsumer(cons); err != nil {
 		log.Error().Err(err).Msg("[api.mjpeg] add consumer")
+		http.Error(w, err.Error(), streams.HTTPStatus(err))
 		return
 	}
 
@@ -183,6 +185,7 @@ func apiStreamY4M(w http.ResponseWriter, r *http.Request) {
 
 	if err := stream.AddConsumer(cons); err != nil {
 		log.Error().Err(err).Caller().Send()
+		http.Error(w, err.Error(), streams.HTTPStatus(err))
 		return
 	}
 
@@ -51,7 +51,7 @@ func handlerKeyframe(w http.ResponseWriter, r *http.Request) {
 
 	if err := stream.AddConsumer(cons); err != nil {
 		log.Error().Err(err).Caller().Send()
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), streams.HTTPStatus(err))
 		return
 	}
 
@@ -105,7 +105,7 @@ func handlerMP4(w http.ResponseWriter, r *http.Request) {
 
 	if err := stream.AddConsumer(cons); err != nil {
 		log.Error().Err(err).Caller().Send()
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), streams.HTTPStatus(err))
 		return
 	}
 
@@ -143,4 +143,8 @@ func handlerMP4(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	_, _ = cons.WriteTo(w)
+
+	if err := stream.CloseReason(cons); err != nil {
+		log.Debug().Err(err).Msg("[mp4] close session")
+	}
 }
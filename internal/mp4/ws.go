@@ -33,7 +33,13 @@ func handlerWSMSE(tr *ws.Transport, msg *ws.Message) error {
 
 	tr.Write(&ws.Message{Type: "mse", Value: mp4.ContentType(cons.Codecs())})
 
-	go cons.WriteTo(tr.Writer())
+	go func() {
+		_, _ = cons.WriteTo(tr.Writer())
+
+		if err := stream.CloseReason(cons); err != nil {
+			tr.Write(&ws.Message{Type: "error", Value: msg.Type + ": " + err.Error()})
+		}
+	}()
 
 	tr.OnClose(func() {
 		stream.RemoveConsumer(cons)
@@ -64,7 +70,13 @@ func handlerWSMP4(tr *ws.Transport, msg *ws.Message) error {
 
 	tr.Write(&ws.Message{Type: "mse", Value: mp4.ContentType(cons.Codecs())})
 
-	go cons.WriteTo(tr.Writer())
+	go func() {
+		_, _ = cons.WriteTo(tr.Writer())
+
+		if err := stream.CloseReason(cons); err != nil {
+			tr.Write(&ws.Message{Type: "error", Value: msg.Type + ": " + err.Error()})
+		}
+	}()
 
 	tr.OnClose(func() {
 		stream.RemoveConsumer(cons)
@@ -0,0 +1,449 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/ffmpeg"
+	"github.com/AlexxIT/go2rtc/internal/record"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/magic"
+	"github.com/AlexxIT/go2rtc/pkg/mjpeg"
+	"github.com/AlexxIT/go2rtc/pkg/mqtt"
+	"github.com/rs/zerolog"
+)
+
+func Init() {
+	var cfg struct {
+		Mod struct {
+			URL       string `yaml:"url"`
+			ClientID  string `yaml:"client_id"`
+			Topic     string `yaml:"topic"`
+			Discovery string `yaml:"discovery"`
+		} `yaml:"mqtt"`
+	}
+
+	// default config
+	cfg.Mod.ClientID = "go2rtc"
+	cfg.Mod.Topic = "go2rtc"
+
+	app.LoadConfig(&cfg)
+
+	if cfg.Mod.URL == "" {
+		return
+	}
+
+	log = app.GetLogger("mqtt")
+
+	s := &server{
+		clientID:  cfg.Mod.ClientID,
+		topic:     cfg.Mod.Topic,
+		discovery: cfg.Mod.Discovery,
+	}
+
+	go func() {
+		for {
+			conn, err := dial(cfg.Mod.URL)
+			if err == nil {
+				u, _ := url.Parse(cfg.Mod.URL)
+				pass, _ := u.User.Password()
+				err = s.serve(conn, u.User.Username(), pass)
+			}
+			log.Warn().Err(err).Msg("[mqtt] reconnect in 5s")
+			time.Sleep(5 * time.Second)
+		}
+	}()
+}
+
+var log zerolog.Logger
+
+// how often check streams status changes
+var statusInterval = 5 * time.Second
+
+// dial support tcp://, mqtt://, ssl://, mqtts:// links
+func dial(rawURL string) (net.Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		if u.Port() == "" {
+			u.Host += ":1883"
+		}
+		return net.DialTimeout("tcp", u.Host, core.ConnDialTimeout)
+	case "ssl", "mqtts":
+		if u.Port() == "" {
+			u.Host += ":8883"
+		}
+		dialer := &net.Dialer{Timeout: core.ConnDialTimeout}
+		return tls.DialWithDialer(dialer, "tcp", u.Host, &tls.Config{ServerName: u.Hostname()})
+	}
+
+	return nil, errors.New("mqtt: unsupported scheme: " + u.Scheme)
+}
+
+type server struct {
+	clientID  string
+	topic     string
+	discovery string
+
+	client   *mqtt.Client
+	statuses map[string]string
+	mu       sync.Mutex
+}
+
+// Command - payload for {topic}/cmd/{action} messages
+type Command struct {
+	Name       string `json:"name"`
+	URL        string `json:"url,omitempty"`
+	Dst        string `json:"dst,omitempty"`
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+// Status - retained payload for {topic}/streams/{name}/status
+type Status struct {
+	Online    bool `json:"online"`
+	Consumers int  `json:"consumers"`
+	Recording bool `json:"recording"`
+}
+
+func (s *server) serve(conn net.Conn, username, password string) error {
+	client := mqtt.NewClient(conn)
+	defer client.Close()
+
+	availability := s.topic + "/availability"
+
+	if err := client.ConnectWill(s.clientID, username, password, availability, []byte("offline")); err != nil {
+		return err
+	}
+
+	log.Info().Str("client_id", s.clientID).Msg("[mqtt] connected")
+
+	s.mu.Lock()
+	s.client = client
+	s.statuses = map[string]string{}
+	s.mu.Unlock()
+
+	if err := client.PublishRetain(availability, []byte("online")); err != nil {
+		return err
+	}
+
+	if err := client.Subscribe(s.topic + "/cmd/+"); err != nil {
+		return err
+	}
+
+	for _, name := range streams.GetAll() {
+		s.publishDiscovery(name)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		ticker := time.NewTicker(statusInterval)
+		defer ticker.Stop()
+
+		for {
+			s.publishStatuses()
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	prefix := s.topic + "/cmd/"
+
+	for {
+		topic, payload, err := client.Read()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				if err = client.Ping(); err == nil {
+					continue
+				}
+			}
+			return err
+		}
+
+		action, ok := strings.CutPrefix(topic, prefix)
+		if !ok {
+			continue
+		}
+
+		log.Debug().Str("action", action).Msgf("[mqtt] command: %s", payload)
+
+		go func() {
+			if err := s.handle(action, payload); err != nil {
+				log.Warn().Err(err).Str("action", action).Msg("[mqtt] command")
+			}
+		}()
+	}
+}
+
+func (s *server) handle(action string, payload []byte) error {
+	var cmd Command
+	if err := json.Unmarshal(payload, &cmd); err != nil {
+		return err
+	}
+
+	if cmd.Name == "" {
+		return errors.New("mqtt: command must specify 'name'")
+	}
+
+	switch action {
+	case "add":
+		if err := checkSource(cmd.URL); err != nil {
+			return err
+		}
+		if streams.New(cmd.Name, cmd.URL) == nil {
+			return errors.New("mqtt: invalid source: " + cmd.URL)
+		}
+		if err := app.PatchState(cmd.Name, cmd.URL, "streams"); err != nil {
+			log.Debug().Err(err).Msg("[mqtt] save state")
+		}
+		s.publishDiscovery(cmd.Name)
+		s.publishStatus(cmd.Name)
+		return nil
+
+	case "remove":
+		if streams.Get(cmd.Name) == nil {
+			return errors.New("mqtt: stream not found: " + cmd.Name)
+		}
+		if record.IsRecording(cmd.Name) {
+			_ = record.Stop(cmd.Name)
+		}
+		streams.Delete(cmd.Name)
+		if err := app.PatchState(cmd.Name, nil, "streams"); err != nil {
+			log.Debug().Err(err).Msg("[mqtt] save state")
+		} else if err = app.PatchState(cmd.Name, nil, "publish"); err != nil {
+			log.Debug().Err(err).Msg("[mqtt] save state")
+		}
+		s.clearStream(cmd.Name)
+		return nil
+	}
+
+	stream := streams.Get(cmd.Name)
+	if stream == nil {
+		return errors.New("mqtt: stream not found: " + cmd.Name)
+	}
+
+	switch action {
+	case "record_start":
+		deviceName := cmd.DeviceName
+		if deviceName == "" {
+			deviceName = cmd.Name
+		}
+		if err := record.Start(cmd.Name, deviceName); err != nil {
+			return err
+		}
+		if err := app.PatchState(cmd.Name, deviceName, "record", "streams"); err != nil {
+			log.Debug().Err(err).Msg("[mqtt] save state")
+		}
+
+	case "record_stop":
+		if err := record.Stop(cmd.Name); err != nil {
+			return err
+		}
+		if err := app.PatchState(cmd.Name, nil, "record", "streams"); err != nil {
+			log.Debug().Err(err).Msg("[mqtt] save state")
+		}
+
+	case "snapshot":
+		b, err := snapshot(stream)
+		if err != nil {
+			return err
+		}
+		return s.publish(s.streamTopic(cmd.Name)+"/snapshot", b, false)
+
+	case "publish":
+		if err := streams.Validate(cmd.Dst); err != nil {
+			return err
+		}
+		if err := checkSource(cmd.Dst); err != nil {
+			return err
+		}
+		if err := stream.Publish(cmd.Dst); err != nil {
+			return err
+		}
+		if err := app.PatchState(cmd.Name, stream.PublishURLs(), "publish"); err != nil {
+			log.Debug().Err(err).Msg("[mqtt] save state")
+		}
+
+	default:
+		return errors.New("mqtt: unknown action: " + action)
+	}
+
+	s.publishStatus(cmd.Name)
+	return nil
+}
+
+// checkSource - MQTT clients have operator role, so exec-style sources are forbidden
+func checkSource(source string) error {
+	if api.SourceRole(source) > api.RoleOperator {
+		return errors.New("mqtt: source not allowed: " + source)
+	}
+	return nil
+}
+
+// snapshot - get one JPEG frame from the stream
+func snapshot(stream *streams.Stream) ([]byte, error) {
+	cons := magic.NewKeyframe()
+	cons.FormatName = "mqtt/jpeg"
+
+	if err := stream.AddConsumer(cons); err != nil {
+		return nil, err
+	}
+
+	once := &core.OnceBuffer{} // init and first frame
+	_, _ = cons.WriteTo(once)
+	b := once.Buffer()
+
+	stream.RemoveConsumer(cons)
+
+	switch cons.CodecName() {
+	case core.CodecH264, core.CodecH265:
+		return ffmpeg.JPEGWithQuery(b, nil)
+	case core.CodecJPEG:
+		return mjpeg.FixJPEG(b), nil
+	}
+
+	return b, nil
+}
+
+func (s *server) publish(topic string, payload []byte, retain bool) error {
+	s.mu.Lock()
+	client := s.client
+	s.mu.Unlock()
+
+	if client == nil {
+		return errors.New("mqtt: not connected")
+	}
+
+	if retain {
+		return client.PublishRetain(topic, payload)
+	}
+	return client.Publish(topic, payload)
+}
+
+// streamTopic - MQTT wildcards not allowed in topic names
+func (s *server) streamTopic(name string) string {
+	name = strings.NewReplacer("+", "_", "#", "_").Replace(name)
+	return s.topic + "/streams/" + name
+}
+
+func (s *server) publishStatuses() {
+	for _, name := range streams.GetAll() {
+		s.publishStatus(name)
+	}
+}
+
+// publishStatus - send retained status only if it was changed
+func (s *server) publishStatus(name string) {
+	stream := streams.Get(name)
+	if stream == nil {
+		return
+	}
+
+	b, _ := json.Marshal(Status{
+		Online:    stream.Online(),
+		Consumers: stream.ConsumersCount(),
+		Recording: record.IsRecording(name),
+	})
+
+	s.mu.Lock()
+	if s.statuses[name] == string(b) {
+		s.mu.Unlock()
+		return
+	}
+	s.statuses[name] = string(b)
+	s.mu.Unlock()
+
+	if err := s.publish(s.streamTopic(name)+"/status", b, true); err != nil {
+		log.Debug().Err(err).Msg("[mqtt] publish status")
+	}
+}
+
+// clearStream - empty retained messages will be removed by broker
+func (s *server) clearStream(name string) {
+	s.mu.Lock()
+	delete(s.statuses, name)
+	s.mu.Unlock()
+
+	_ = s.publish(s.streamTopic(name)+"/status", nil, true)
+
+	if s.discovery != "" {
+		id := objectID(name)
+		_ = s.publish(s.discovery+"/camera/"+id+"/config", nil, true)
+		_ = s.publish(s.discovery+"/binary_sensor/"+id+"/config", nil, true)
+	}
+}
+
+// publishDiscovery - Home Assistant MQTT discovery
+// https://www.home-assistant.io/integrations/mqtt/#mqtt-discovery
+func (s *server) publishDiscovery(name string) {
+	if s.discovery == "" {
+		return
+	}
+
+	id := objectID(name)
+	topic := s.streamTopic(name)
+	device := map[string]any{
+		"identifiers":  []string{id},
+		"name":         name,
+		"manufacturer": "go2rtc",
+		"sw_version":   app.Version,
+	}
+
+	camera := map[string]any{
+		"name":                  nil, // use device name
+		"unique_id":             id + "_camera",
+		"topic":                 topic + "/snapshot",
+		"availability_topic":    s.topic + "/availability",
+		"json_attributes_topic": topic + "/status",
+		"device":                device,
+	}
+
+	sensor := map[string]any{
+		"name":               "Online",
+		"unique_id":          id + "_online",
+		"state_topic":        topic + "/status",
+		"value_template":     "{{ 'ON' if value_json.online else 'OFF' }}",
+		"device_class":       "connectivity",
+		"availability_topic": s.topic + "/availability",
+		"device":             device,
+	}
+
+	b, _ := json.Marshal(camera)
+	if err := s.publish(s.discovery+"/camera/"+id+"/config", b, true); err != nil {
+		log.Debug().Err(err).Msg("[mqtt] publish discovery")
+		return
+	}
+
+	b, _ = json.Marshal(sensor)
+	_ = s.publish(s.discovery+"/binary_sensor/"+id+"/config", b, true)
+}
+
+// objectID - Home Assistant allows only [a-zA-Z0-9_-] symbols
+func objectID(name string) string {
+	b := []byte("go2rtc_" + name)
+	for i, c := range b {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' {
+			continue
+		}
+		b[i] = '_'
+	}
+	return string(b)
+}
@@ -0,0 +1,143 @@
+package mqtt
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/mqtt"
+	"github.com/stretchr/testify/require"
+)
+
+type message struct {
+	topic   string
+	payload string
+	retain  bool
+}
+
+// broker - minimal MQTT broker stand-in, that accept any client,
+// test should wait for the exit, because of t.Error
+func broker(t *testing.T, conn net.Conn, messages chan<- message) {
+	for {
+		b := make([]byte, 1)
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return
+		}
+
+		size, err := mqtt.ReadLen(conn)
+		if err != nil {
+			brokerError(t, err)
+			return
+		}
+
+		body := make([]byte, size)
+		if _, err = io.ReadFull(conn, body); err != nil {
+			brokerError(t, err)
+			return
+		}
+
+		switch b[0] & 0xF0 {
+		case mqtt.CONNECT:
+			_, _ = conn.Write([]byte{mqtt.CONNACK, 2, 0, 0})
+		case mqtt.PUBLISH:
+			i := binary.BigEndian.Uint16(body)
+			topic, payload := string(body[2:2+i]), body[2+i:]
+			if b[0]&mqtt.QOS1 != 0 {
+				payload = payload[2:] // skip packet ID
+			}
+			// don't block the exit when test stopped reading
+			select {
+			case messages <- message{topic: topic, payload: string(payload), retain: b[0]&mqtt.RETAIN != 0}:
+			default:
+			}
+		}
+	}
+}
+
+// brokerError - closed pipe is the normal test exit
+func brokerError(t *testing.T, err error) {
+	if !errors.Is(err, io.ErrClosedPipe) {
+		t.Error(err)
+	}
+}
+
+func waitMessage(t *testing.T, messages <-chan message, topic string) message {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case msg := <-messages:
+			if msg.topic == topic {
+				return msg
+			}
+		case <-timeout:
+			require.FailNow(t, "no message: "+topic)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	conn, brokerConn := net.Pipe()
+
+	messages := make(chan message, 100)
+	brokerDone := make(chan struct{})
+	go func() {
+		broker(t, brokerConn, messages)
+		close(brokerDone)
+	}()
+
+	s := &server{clientID: "go2rtc", topic: "go2rtc", discovery: "homeassistant"}
+	serveDone := make(chan struct{})
+	go func() {
+		_ = s.serve(conn, "", "")
+		close(serveDone)
+	}()
+
+	defer func() {
+		_ = brokerConn.Close()
+		<-serveDone
+		<-brokerDone
+	}()
+
+	msg := waitMessage(t, messages, "go2rtc/availability")
+	require.Equal(t, message{"go2rtc/availability", "online", true}, msg)
+
+	b := mqtt.NewPublish("go2rtc/cmd/add", []byte(`{"name":"camera1","url":"rtsp://192.168.1.123/stream"}`))
+	_, err := brokerConn.Write(b.Bytes())
+	require.Nil(t, err)
+
+	msg = waitMessage(t, messages, "homeassistant/camera/go2rtc_camera1/config")
+	require.True(t, msg.retain)
+	require.Contains(t, msg.payload, `"topic":"go2rtc/streams/camera1/snapshot"`)
+
+	msg = waitMessage(t, messages, "go2rtc/streams/camera1/status")
+	require.Equal(t, message{"go2rtc/streams/camera1/status", `{"online":false,"consumers":0,"recording":false}`, true}, msg)
+	require.NotNil(t, streams.Get("camera1"))
+
+	b = mqtt.NewPublish("go2rtc/cmd/remove", []byte(`{"name":"camera1"}`))
+	_, err = brokerConn.Write(b.Bytes())
+	require.Nil(t, err)
+
+	msg = waitMessage(t, messages, "go2rtc/streams/camera1/status")
+	require.Equal(t, message{"go2rtc/streams/camera1/status", "", true}, msg)
+	require.Nil(t, streams.Get("camera1"))
+}
+
+func TestCommandsSourceRole(t *testing.T) {
+	streams.HandleFunc("echo", func(string) (core.Producer, error) { return nil, nil })
+	streams.HandleFunc("ffmpeg", func(string) (core.Producer, error) { return nil, nil })
+
+	s := &server{}
+
+	err := s.handle("add", []byte(`{"name":"camera1","url":"echo:/usr/bin/id"}`))
+	require.NotNil(t, err)
+	require.Nil(t, streams.Get("camera1"))
+
+	err = s.handle("add", []byte(`{"name":"camera1","url":"ffmpeg:rtsp://10.0.0.1/stream#raw=-y"}`))
+	require.NotNil(t, err)
+	require.Nil(t, streams.Get("camera1"))
+}
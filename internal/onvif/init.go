@@ -5,7 +5,6 @@ import (
 	"net"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
 	"time"
 
@@ -90,7 +89,7 @@ func onvifDeviceService(w http.ResponseWriter, r *http.Request) {
 		res = onvif.SystemRebootResponse()
 
 		time.AfterFunc(time.Second, func() {
-			os.Exit(0)
+			app.Shutdown(0)
 		})
 
 	case onvif.ActionGetProfiles:
@@ -0,0 +1,93 @@
+package record
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+)
+
+func initAPIv2() {
+	api.HandleRoute(&api.Route{
+		Method: "GET", Path: "recordings", Summary: "List active recordings",
+	}, apiV2Recordings)
+	api.HandleRoute(&api.Route{
+		Method: "PUT", Path: "recordings/{name}", Summary: "Start stream recording", Status: http.StatusCreated,
+		Body: `{"device_name":"Voskhod-26 (entrance 1)"}`,
+	}, apiV2RecordingStart)
+	api.HandleRoute(&api.Route{
+		Method: "DELETE", Path: "recordings/{name}", Summary: "Stop stream recording", Status: http.StatusNoContent,
+	}, apiV2RecordingStop)
+}
+
+type recordingInfo struct {
+	Stream       string `json:"stream"`
+	BytesWritten int64  `json:"bytes_written"`
+}
+
+func apiV2Recordings(w http.ResponseWriter, r *http.Request) {
+	infos := []*recordingInfo{}
+	for name, written := range BytesWritten() {
+		infos = append(infos, &recordingInfo{Stream: name, BytesWritten: written})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Stream < infos[j].Stream })
+
+	api.ResponseStatusJSON(w, http.StatusOK, infos)
+}
+
+func apiV2RecordingStart(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if streams.Get(name) == nil {
+		api.ResponseError(w, http.StatusNotFound, api.ErrNotFound)
+		return
+	}
+
+	var body struct {
+		DeviceName string `json:"device_name"`
+	}
+	if err := api.DecodeJSON(r, &body); err != nil {
+		api.ResponseError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.DeviceName == "" {
+		body.DeviceName = name
+	}
+
+	if IsRecording(name) {
+		api.ResponseError(w, http.StatusConflict, api.ErrConflict)
+		return
+	}
+
+	if err := Start(name, body.DeviceName); err != nil {
+		api.ResponseError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := app.PatchState(name, body.DeviceName, "record", "streams"); err != nil {
+		log.Debug().Err(err).Msg("[record] save state")
+	}
+
+	api.ResponseStatusJSON(w, http.StatusCreated, &recordingInfo{Stream: name})
+}
+
+func apiV2RecordingStop(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !IsRecording(name) {
+		api.ResponseError(w, http.StatusNotFound, errors.New("not recording"))
+		return
+	}
+
+	if err := Stop(name); err != nil {
+		api.ResponseError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := app.PatchState(name, nil, "record", "streams"); err != nil {
+		log.Debug().Err(err).Msg("[record] save state")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
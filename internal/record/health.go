@@ -0,0 +1,61 @@
+package record
+
+import (
+	"errors"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+)
+
+// writeTimeout - recording without writes is stalled
+var writeTimeout = 30 * time.Second
+
+type recorderHealth struct {
+	Writing      bool       `json:"writing"`
+	LastWrite    *time.Time `json:"last_write,omitempty"`
+	BytesWritten int64      `json:"bytes_written"`
+}
+
+func initHealth() {
+	streams.OnHealth(func(h *streams.StreamHealth) {
+		recordingsMu.Lock()
+		seg := recordings[h.Name]
+		recordingsMu.Unlock()
+
+		if seg != nil {
+			h.Recorder = seg.health()
+		}
+	})
+
+	app.AddReadyCheck("record", func() error {
+		recordingsMu.Lock()
+		defer recordingsMu.Unlock()
+
+		for name, seg := range recordings {
+			if seg.stalled() {
+				return errors.New("record: not writing: " + name)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Segments) health() *recorderHealth {
+	h := &recorderHealth{BytesWritten: s.written.Load()}
+	if ts := s.lastWrite.Load(); ts != 0 {
+		t := time.Unix(0, ts)
+		h.LastWrite = &t
+		h.Writing = time.Since(t) < writeTimeout
+	}
+	return h
+}
+
+// stalled - no writes for timeout, new recording has time for the first write
+func (s *Segments) stalled() bool {
+	last := s.created
+	if ts := s.lastWrite.Load(); ts != 0 {
+		last = time.Unix(0, ts)
+	}
+	return time.Since(last) > writeTimeout
+}
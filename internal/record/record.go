@@ -1,16 +1,33 @@
 package record
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/streams"
 	"github.com/rs/zerolog"
 )
 
+const (
+	EventRecordStart  streams.Event = "record_start"
+	EventRecordStop   streams.Event = "record_stop"
+	EventRecordFailed streams.Event = "record_failed"
+)
+
 var log zerolog.Logger
 var recordings = map[string]*Segments{}
+var recordingsMu sync.Mutex
+
+var (
+	basePath        string
+	segmentDuration time.Duration
+	numSegments     int
+	timezone        *time.Location
+)
 
 func Init() {
 	log = app.GetLogger("record")
@@ -24,53 +41,147 @@ func Init() {
 
 	app.LoadConfig(&cfg)
 
-	basePath, ok := cfg.Record["basePath"].(string)
+	var ok bool
+	var err error
+
+	basePath, ok = cfg.Record["basePath"].(string)
 	if !ok {
 		log.Fatal().Msg("record.basePath is invalid")
 	}
 
 	segmentDurationStr, ok := cfg.Record["segmentDuration"].(string)
-	segmentDuration, err := time.ParseDuration(segmentDurationStr)
+	segmentDuration, err = time.ParseDuration(segmentDurationStr)
 	if !ok || err != nil {
 		log.Fatal().Msg("record.segmentDuration is invalid")
 	}
 
 	timezoneStr, ok := cfg.Record["timezone"].(string)
-	timezone, err := time.LoadLocation(timezoneStr)
+	timezone, err = time.LoadLocation(timezoneStr)
 	if !ok || err != nil {
 		log.Fatal().Msg("record.timezone is invalid")
 	}
 
-	numSegments, ok := cfg.Record["numSegments"].(int)
+	numSegments, ok = cfg.Record["numSegments"].(int)
 	if !ok {
 		log.Fatal().Msg("record.numSegments is invalid")
 	}
 
+	// close current segments before the streams module stops the sources
+	app.OnShutdown("record", stopAll)
+
+	initAPIv2()
+	initHealth()
+
+	// recording toggles from runtime state: device name or null
+	toggles, _ := cfg.Record["streams"].(map[string]any)
+
 	for streamName, item := range cfg.Streams {
-		switch item := item.(type) {
-		case map[string]any:
-			deviceName, ok := item["device_name"].(string)
-			if !ok {
-				continue
-			}
-			deviceName = strings.ReplaceAll(deviceName, "/", "-")
-			gateAddress := strings.Split(deviceName, " (")[0] // встретимся как попадется адрес со скобками
-			seg, err := NewSegments(
-				segmentDuration,
-				numSegments,
-				fmt.Sprintf("%s/%s/%s", basePath, gateAddress, deviceName),
-				timezone,
-				streamName,
-			)
-			if err != nil {
-				log.Fatal().Err(err).Msg("failed to create segments")
-			}
-			recordings[streamName] = seg
-
-			go seg.Record()
-			time.Sleep(time.Second * 2) // sleep couple seconds so streams won't switch segments all at the same time
-		default:
+		// stream was deleted at runtime
+		if item == nil {
 			continue
 		}
+
+		// device name from the stream config, runtime toggle works for any stream type
+		var deviceName string
+		var ok bool
+		if item, isMap := item.(map[string]any); isMap {
+			deviceName, ok = item["device_name"].(string)
+		}
+		if toggle, exists := toggles[streamName]; exists {
+			deviceName, ok = toggle.(string)
+		}
+		if !ok {
+			continue
+		}
+
+		if err = Start(streamName, deviceName); err != nil {
+			log.Fatal().Err(err).Msg("failed to create segments")
+		}
+		time.Sleep(time.Second * 2) // sleep couple seconds so streams won't switch segments all at the same time
+	}
+}
+
+// Start recording of the stream into the folder based on the device name
+func Start(streamName, deviceName string) error {
+	recordingsMu.Lock()
+	defer recordingsMu.Unlock()
+
+	if basePath == "" {
+		return errors.New("record: module not configured")
+	}
+
+	if _, ok := recordings[streamName]; ok {
+		return errors.New("record: already recording: " + streamName)
+	}
+
+	// support templates: "{labels.gate} ({labels.entrance})"
+	if stream := streams.Get(streamName); stream != nil {
+		deviceName = stream.Expand(deviceName)
+	}
+
+	deviceName = strings.ReplaceAll(deviceName, "/", "-")
+	gateAddress := strings.Split(deviceName, " (")[0] // встретимся как попадется адрес со скобками
+	seg, err := NewSegments(
+		segmentDuration,
+		numSegments,
+		fmt.Sprintf("%s/%s/%s", basePath, gateAddress, deviceName),
+		timezone,
+		streamName,
+	)
+	if err != nil {
+		return err
+	}
+	recordings[streamName] = seg
+
+	go seg.Record()
+
+	return nil
+}
+
+// Stop recording of the stream and close the current segment
+func Stop(streamName string) error {
+	recordingsMu.Lock()
+	seg, ok := recordings[streamName]
+	delete(recordings, streamName)
+	recordingsMu.Unlock()
+
+	if !ok {
+		return errors.New("record: not recording: " + streamName)
+	}
+
+	seg.Stop()
+
+	return nil
+}
+
+func stopAll() {
+	recordingsMu.Lock()
+	names := make([]string, 0, len(recordings))
+	for name := range recordings {
+		names = append(names, name)
+	}
+	recordingsMu.Unlock()
+
+	for _, name := range names {
+		_ = Stop(name)
+	}
+}
+
+func IsRecording(streamName string) bool {
+	recordingsMu.Lock()
+	_, ok := recordings[streamName]
+	recordingsMu.Unlock()
+	return ok
+}
+
+// BytesWritten - bytes written by active recordings, by stream name
+func BytesWritten() map[string]int64 {
+	recordingsMu.Lock()
+	defer recordingsMu.Unlock()
+
+	written := make(map[string]int64, len(recordings))
+	for name, seg := range recordings {
+		written[name] = seg.written.Load()
 	}
+	return written
 }
@@ -2,9 +2,12 @@ package record
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/AlexxIT/go2rtc/internal/streams"
@@ -29,12 +32,23 @@ type Segments struct {
 	stream     *streams.Stream
 	medias     []*core.Media
 	cons       *mp4.Consumer
+
+	done      chan struct{}
+	mu        sync.Mutex
+	written   atomic.Int64
+	created   time.Time
+	lastWrite atomic.Int64 // unix nanoseconds
 }
 
 func NewSegments(
 	segmentDuration time.Duration, numSegments int,
 	path string, filenameTZ *time.Location, streamName string,
 ) (segments *Segments, err error) {
+	stream := streams.Get(streamName)
+	if stream == nil {
+		return nil, errors.New("record: stream not found: " + streamName)
+	}
+
 	segments = &Segments{
 		segmentDuration: segmentDuration,
 		numSegments:     numSegments,
@@ -42,8 +56,10 @@ func NewSegments(
 		filenameTZ:      filenameTZ,
 		files:           make([]*os.File, numSegments),
 		streamName:      streamName,
-		stream:          streams.Get(streamName),
+		stream:          stream,
 		medias:          mp4.ParseQuery(map[string][]string{"src": {streamName}, "mp4": {"all"}}),
+		done:            make(chan struct{}),
+		created:         time.Now(),
 	}
 	err = os.MkdirAll(path, 0750)
 	if err != nil {
@@ -54,32 +70,75 @@ func NewSegments(
 }
 
 func (s *Segments) Write(b []byte) (n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if bytes.HasPrefix(b, mp4MagicNumber) {
 		s.switchFile()
 	}
-	return s.files[s.current].Write(b)
+	if s.files[s.current] == nil {
+		return 0, errors.New("record: segment closed")
+	}
+	n, err = s.files[s.current].Write(b)
+	s.written.Add(int64(n))
+	if err == nil {
+		s.lastWrite.Store(time.Now().UnixNano())
+	}
+	return
 }
 
 func (s *Segments) Record() {
+	s.mu.Lock()
 	s.prepareNextFile()
-
 	s.cons = mp4.NewConsumer(s.medias)
+	s.mu.Unlock()
 
 	for {
-		err := s.stream.AddConsumer(s.cons)
+		err := s.stream.AddConsumerWithPriority(s.cons, streams.PriorityRecorder)
 		if err == nil {
 			break
 		}
 		log.Error().Err(err).Msgf("failed to add a recording consumer (%s), retrying...", s.streamName)
-		time.Sleep(30 * time.Second)
+		streams.FireEvent(&streams.Message{Event: EventRecordFailed, Stream: s.streamName, Error: err.Error()})
+
+		select {
+		case <-time.After(30 * time.Second):
+		case <-s.done:
+			return
+		}
 	}
 	go func() {
 		_, _ = s.cons.WriteTo(s) // blocks
 	}()
 
+	streams.FireEvent(&streams.Message{Event: EventRecordStart, Stream: s.streamName, FormatName: s.cons.FormatName})
+
 	s.scheduleSwitch()
 }
 
+// Stop remove recording consumer from the stream and close all segment files
+func (s *Segments) Stop() {
+	close(s.done)
+
+	s.mu.Lock()
+	cons := s.cons
+	s.mu.Unlock()
+
+	if cons != nil {
+		s.stream.RemoveConsumer(cons)
+		streams.FireEvent(&streams.Message{Event: EventRecordStop, Stream: s.streamName})
+	}
+
+	s.mu.Lock()
+	for i, file := range s.files {
+		if file != nil {
+			_ = file.Close()
+			s.files[i] = nil
+		}
+	}
+	s.mu.Unlock()
+}
+
 func (s *Segments) switchFile() {
 	prev := s.current
 	s.current++
@@ -96,7 +155,7 @@ func (s *Segments) switchFile() {
 func (s *Segments) prepareNextFile() {
 	var err error
 
-	now := time.Now().In(s.filenameTZ)
+	now := s.now().In(s.filenameTZ)
 	filename := fmt.Sprintf(
 		"%s/.%s_%s_raw.mp4",
 		s.path,
@@ -110,6 +169,7 @@ func (s *Segments) prepareNextFile() {
 	)
 	if err != nil {
 		log.Error().Err(err).Msg("failed to open new segment file")
+		streams.FireEvent(&streams.Message{Event: EventRecordFailed, Stream: s.streamName, Error: err.Error()})
 	}
 
 	next := s.current + 1
@@ -129,9 +189,31 @@ func (s *Segments) prepareNextFile() {
 	s.files[next] = newFile
 }
 
+// now - source capture time if source has wall clock mapping (RTSP with RTCP)
+func (s *Segments) now() time.Time {
+	if s.cons != nil {
+		for _, sender := range s.cons.Senders {
+			if clock := sender.Clock(); clock != nil {
+				return time.Now().Add(clock.Offset())
+			}
+		}
+	}
+	return time.Now()
+}
+
 func (s *Segments) scheduleSwitch() {
-	for range time.NewTicker(s.segmentDuration).C {
-		s.prepareNextFile()
-		s.cons.ResetMuxer() // trigger the muxer to send mp4 magic number
+	ticker := time.NewTicker(s.segmentDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			s.prepareNextFile()
+			s.mu.Unlock()
+			s.cons.ResetMuxer() // trigger the muxer to send mp4 magic number
+		case <-s.done:
+			return
+		}
 	}
 }
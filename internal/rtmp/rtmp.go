@@ -5,6 +5,8 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/AlexxIT/go2rtc/internal/api"
 	"github.com/AlexxIT/go2rtc/internal/app"
@@ -44,11 +46,17 @@ func Init() {
 	ln, err := net.Listen("tcp", address)
 	if err != nil {
 		log.Error().Err(err).Caller().Send()
+		app.AddHealthCheck("rtmp", func() error { return err })
 		return
 	}
 
 	log.Info().Str("addr", address).Msg("[rtmp] listen")
 
+	// stop accepting new connections
+	app.OnShutdown("rtmp", func() {
+		_ = ln.Close()
+	})
+
 	go func() {
 		for {
 			conn, err := ln.Accept()
@@ -75,14 +83,63 @@ func tcpHandle(netConn net.Conn) error {
 		return err
 	}
 
+	// access token can be in the app or in the stream name query: rtmp://host/camera1?token=xxx
+	name, rawQuery, _ := strings.Cut(rtmpConn.App, "?")
+	if rawQuery == "" {
+		_, rawQuery, _ = strings.Cut(rtmpConn.Stream, "?")
+	}
+	query, _ := url.ParseQuery(rawQuery)
+	token := query.Get("token")
+
+	remoteAddr := netConn.RemoteAddr().String()
+
+	var session *api.Session
+	if rtmpConn.Intent == rtmp.CommandPlay {
+		t, err := api.Authorize(token, name, "rtmp", remoteAddr)
+		if err != nil {
+			return err
+		}
+		if t != nil {
+			session = &api.Session{User: t.Subject, Token: t.ID}
+		}
+	}
+
+	if session == nil && api.AuthHookEnabled() && !netConn.RemoteAddr().(*net.TCPAddr).IP.IsLoopback() {
+		action := "play"
+		if rtmpConn.Intent == rtmp.CommandPublish {
+			action = "publish"
+		}
+
+		// RTMP doesn't have auth, so credentials can be only in the query
+		limits, err := api.AuthHook(&api.AuthRequest{
+			Protocol:   "rtmp",
+			Action:     action,
+			Stream:     name,
+			RemoteAddr: remoteAddr,
+			Username:   query.Get("user"),
+			Password:   query.Get("pass"),
+		})
+		if err != nil {
+			return err
+		}
+
+		session = &api.Session{User: query.Get("user"), Limits: limits}
+	}
+
 	switch rtmpConn.Intent {
 	case rtmp.CommandPlay:
-		stream := streams.Get(rtmpConn.App)
+
+		stream := streams.Get(name)
 		if stream == nil {
-			return errors.New("stream not found: " + rtmpConn.App)
+			return errors.New("stream not found: " + name)
 		}
 
 		cons := flv.NewConsumer()
+		cons.Protocol = "rtmp"
+		cons.RemoteAddr = remoteAddr
+		if session != nil {
+			cons.Session = session
+		}
 		if err = stream.AddConsumer(cons); err != nil {
 			return err
 		}
@@ -98,9 +155,14 @@ func tcpHandle(netConn net.Conn) error {
 		return nil
 
 	case rtmp.CommandPublish:
-		stream := streams.Get(rtmpConn.App)
+		// access tokens are only for watching
+		if token != "" {
+			return errors.New("rtmp: access token not allowed for publish")
+		}
+
+		stream := streams.Get(name)
 		if stream == nil {
-			return errors.New("stream not found: " + rtmpConn.App)
+			return errors.New("stream not found: " + name)
 		}
 
 		if err = rtmpConn.WriteStart(); err != nil {
@@ -164,6 +226,7 @@ func outputFLV(w http.ResponseWriter, r *http.Request) {
 
 	if err := stream.AddConsumer(cons); err != nil {
 		log.Error().Err(err).Caller().Send()
+		http.Error(w, err.Error(), streams.HTTPStatus(err))
 		return
 	}
 
@@ -1,11 +1,16 @@
 package rtsp
 
 import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"io"
 	"net"
 	"net/url"
 	"strings"
+	"time"
 
+	"github.com/AlexxIT/go2rtc/internal/api"
 	"github.com/AlexxIT/go2rtc/internal/app"
 	"github.com/AlexxIT/go2rtc/internal/streams"
 	"github.com/AlexxIT/go2rtc/pkg/core"
@@ -22,6 +27,8 @@ func Init() {
 			Password     string `yaml:"password" json:"-"`
 			DefaultQuery string `yaml:"default_query" json:"default_query"`
 			PacketSize   uint16 `yaml:"pkt_size" json:"pkt_size,omitempty"`
+
+			JitterBuffer time.Duration `yaml:"jitter_buffer" json:"jitter_buffer,omitempty"`
 		} `yaml:"rtsp"`
 	}
 
@@ -34,6 +41,8 @@ func Init() {
 
 	log = app.GetLogger("rtsp")
 
+	rtsp.JitterLatency = conf.Mod.JitterBuffer
+
 	// RTSP client support
 	streams.HandleFunc("rtsp", rtspHandler)
 	streams.HandleFunc("rtsps", rtspHandler)
@@ -48,6 +57,7 @@ func Init() {
 	ln, err := net.Listen("tcp", address)
 	if err != nil {
 		log.Error().Err(err).Msg("[rtsp] listen")
+		app.AddHealthCheck("rtsp", func() error { return err })
 		return
 	}
 
@@ -55,6 +65,11 @@ func Init() {
 
 	log.Info().Str("addr", address).Msg("[rtsp] listen")
 
+	// stop accepting new connections
+	app.OnShutdown("rtsp", func() {
+		_ = ln.Close()
+	})
+
 	if query, err := url.ParseQuery(conf.Mod.DefaultQuery); err == nil {
 		defaultMedias = ParseQuery(query)
 	}
@@ -69,9 +84,11 @@ func Init() {
 			c := rtsp.NewServer(conn)
 			c.PacketSize = conf.Mod.PacketSize
 			// skip check auth for localhost
-			if conf.Mod.Username != "" && !conn.RemoteAddr().(*net.TCPAddr).IP.IsLoopback() {
+			local := conn.RemoteAddr().(*net.TCPAddr).IP.IsLoopback()
+			if conf.Mod.Username != "" && !local {
 				c.Auth(conf.Mod.Username, conf.Mod.Password)
 			}
+			authHandler(c, conf.Mod.Username, conf.Mod.Password, local)
 			go tcpHandler(c)
 		}
 	}()
@@ -142,6 +159,134 @@ func rtspHandler(rawURL string) (core.Producer, error) {
 	return conn, nil
 }
 
+// authHandler - check access token from the first request URL and the auth hook
+// on DESCRIBE and ANNOUNCE, both replace username and password check
+func authHandler(conn *rtsp.Conn, username, password string, local bool) {
+	var checked, byToken, byHook bool
+	var token string
+
+	withAuth := username != "" && !local
+	withHook := api.AuthHookEnabled() && !local
+
+	conn.Listen(func(msg any) {
+		req, ok := msg.(*tcp.Request)
+		if !ok {
+			return
+		}
+
+		if !checked {
+			checked = true
+
+			name := requestStream(req)
+			token = req.URL.Query().Get("token")
+
+			t, err := api.Authorize(token, name, "rtsp", conn.RemoteAddr)
+			if err != nil {
+				log.Debug().Err(err).Str("stream", name).Msg("[rtsp] token")
+			}
+
+			if byToken = t != nil; byToken {
+				conn.Session = &api.Session{User: t.Subject, Token: t.ID}
+			}
+
+			switch {
+			case byToken:
+				conn.Authorize(true)
+			case err != nil && (token != "" || !withAuth):
+				conn.Authorize(false)
+			}
+		}
+
+		if byToken {
+			switch req.Method {
+			case rtsp.MethodAnnounce, rtsp.MethodRecord:
+				// access tokens are only for watching
+				conn.Authorize(false)
+			case rtsp.MethodDescribe, rtsp.MethodSetup, rtsp.MethodPlay:
+				// each request can address another stream
+				name := requestStream(req)
+				if _, err := api.Authorize(token, name, "rtsp", conn.RemoteAddr); err != nil {
+					log.Debug().Err(err).Str("stream", name).Msg("[rtsp] token")
+					conn.Authorize(false)
+				}
+			}
+			return
+		}
+
+		if !withHook {
+			// remember user for the audit, wrong credentials will be rejected by the conn
+			if withAuth && (req.Method == rtsp.MethodDescribe || req.Method == rtsp.MethodAnnounce) {
+				if user, _ := basicAuth(req); user != "" {
+					conn.Session = &api.Session{User: user}
+				}
+			}
+			return
+		}
+
+		switch req.Method {
+		case rtsp.MethodOptions:
+			conn.Authorize(true)
+		case rtsp.MethodDescribe, rtsp.MethodAnnounce:
+			byHook = authHook(conn, req, username, password)
+			conn.Authorize(byHook)
+		default:
+			conn.Authorize(byHook)
+		}
+	})
+}
+
+// requestStream - stream name from the request path, without track for SETUP: /camera1/trackID=0
+func requestStream(req *tcp.Request) string {
+	name := strings.TrimPrefix(req.URL.Path, "/")
+	if i := strings.LastIndexByte(name, '/'); i >= 0 && strings.HasPrefix(name[i+1:], "trackID=") {
+		name = name[:i]
+	}
+	return name
+}
+
+func basicAuth(req *tcp.Request) (user, pass string) {
+	if s, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Basic "); ok {
+		b, _ := base64.StdEncoding.DecodeString(s)
+		user, pass, _ = strings.Cut(string(b), ":")
+	}
+	return
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func authHook(conn *rtsp.Conn, req *tcp.Request, username, password string) bool {
+	user, pass := basicAuth(req)
+
+	if username != "" && equal(user, username) && equal(pass, password) {
+		conn.Session = &api.Session{User: user}
+		return true
+	}
+
+	hookReq := &api.AuthRequest{
+		Protocol:   "rtsp",
+		Action:     "play",
+		Stream:     strings.TrimPrefix(req.URL.Path, "/"),
+		RemoteAddr: conn.RemoteAddr,
+		Username:   user,
+		Password:   pass,
+		UserAgent:  req.Header.Get("User-Agent"),
+	}
+	if req.Method == rtsp.MethodAnnounce {
+		hookReq.Action = "publish"
+	}
+
+	limits, err := api.AuthHook(hookReq)
+	if err != nil {
+		log.Debug().Err(err).Str("stream", hookReq.Stream).Msg("[rtsp] auth hook")
+		return false
+	}
+
+	conn.Session = &api.Session{User: user, Limits: limits}
+	return true
+}
+
 func tcpHandler(conn *rtsp.Conn) {
 	var name string
 	var closer func()
@@ -190,10 +335,16 @@ func tcpHandler(conn *rtsp.Conn) {
 
 			if err := stream.AddConsumer(conn); err != nil {
 				log.Warn().Err(err).Str("stream", name).Msg("[rtsp]")
+				if errors.Is(err, streams.ErrConsumersLimit) {
+					conn.Reject = "453 Not Enough Bandwidth"
+				}
 				return
 			}
 
 			closer = func() {
+				if err := stream.CloseReason(conn); err != nil {
+					log.Debug().Err(err).Str("stream", name).Msg("[rtsp] close session")
+				}
 				stream.RemoveConsumer(conn)
 			}
 
@@ -2,22 +2,65 @@ package streams
 
 import (
 	"errors"
-	"fmt"
 	"strings"
+	"time"
 
+	"github.com/AlexxIT/go2rtc/internal/app"
 	"github.com/AlexxIT/go2rtc/pkg/core"
 )
 
-const CONSUMERS_PER_STREAM_LIMIT = 3
+// AddConsumer - add consumer with priority and session limits from config by consumer format
+// or with session limits from the auth hook
+func (s *Stream) AddConsumer(cons core.Consumer) error {
+	format := formatName(cons)
+	priority := consumersPriorities[format]
+	session := consumersSessions.get(format)
 
-func (s *Stream) AddConsumer(cons core.Consumer) (err error) {
-	if len(s.consumers) >= CONSUMERS_PER_STREAM_LIMIT {
-		return fmt.Errorf("reached limit of consumers per stream (<= %d)", CONSUMERS_PER_STREAM_LIMIT)
+	limits := sessionLimits(cons)
+	if limits != nil && limits.Priority != "" {
+		priority = ParsePriority(limits.Priority)
+	}
+
+	if err := checkSessionsPerIP(cons, session.MaxPerIP); err != nil {
+		return err
+	}
+
+	if err := s.AddConsumerWithPriority(cons, priority); err != nil {
+		return err
+	}
+
+	maxDuration := session.MaxDuration
+	if limits != nil && limits.MaxDuration > 0 {
+		if d := time.Duration(limits.MaxDuration) * time.Second; maxDuration == 0 || d < maxDuration {
+			maxDuration = d
+		}
+	}
+
+	if maxDuration > 0 {
+		s.limitDuration(cons, maxDuration)
+	}
+
+	if session.IdleTimeout > 0 {
+		go s.limitIdle(cons, session.IdleTimeout)
+	}
+
+	return nil
+}
+
+func (s *Stream) AddConsumerWithPriority(cons core.Consumer, priority Priority) (err error) {
+	if app.ShuttingDown() {
+		return ErrShutdown
 	}
 
 	// support for multiple simultaneous pending from different consumers
 	consN := s.pending.Add(1) - 1
 
+	// check limits after pending, so evicted consumers won't stop producers
+	if err = s.checkLimits(cons, priority); err != nil {
+		s.pending.Add(-1)
+		return err
+	}
+
 	var prodErrors = make([]error, len(s.producers))
 	var prodMedias []*core.Media
 	var prodStarts []*Producer
@@ -64,7 +107,7 @@ func (s *Stream) AddConsumer(cons core.Consumer) (err error) {
 						continue
 					}
 					// Step 5. Add track to consumer
-					if err = cons.AddTrack(consMedia, consCodec, track); err != nil {
+					if err = prod.addConsumerTrack(cons, consMedia, consCodec, track); err != nil {
 						log.Info().Err(err).Msg("[streams] can't add track")
 						continue
 					}
@@ -100,18 +143,37 @@ func (s *Stream) AddConsumer(cons core.Consumer) (err error) {
 	}
 
 	if len(prodStarts) == 0 {
+		s.mu.Lock()
+		s.releaseLocked(cons)
+		s.mu.Unlock()
 		return formatError(consMedias, prodMedias, prodErrors)
 	}
 
 	s.mu.Lock()
+	s.releaseLocked(cons)
 	s.consumers = append(s.consumers, cons)
+	if s.priorities == nil {
+		s.priorities = map[core.Consumer]Priority{}
+	}
+	s.priorities[cons] = priority
+	if s.started == nil {
+		s.started = map[core.Consumer]time.Time{}
+	}
+	s.started[cons] = time.Now()
 	s.mu.Unlock()
 
+	s.fireConsumerEvent(EventConsumerConnect, cons, time.Time{}, nil)
+
 	// there may be duplicates, but that's not a problem
 	for _, prod := range prodStarts {
 		prod.start()
 	}
 
+	// primary source may be unavailable, so consumer uses backup source
+	if s.failover {
+		go s.failback()
+	}
+
 	return nil
 }
 
@@ -12,9 +12,18 @@ func apiStreams(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	src := query.Get("src")
 
-	// without source - return all streams list
+	// without source - return all streams list, optionally filtered by labels
 	if src == "" && r.Method != "POST" {
-		api.ResponseJSON(w, streams)
+		// slow client shouldn't block other streams operations
+		all := Streams()
+		if selectors := parseSelectors(query["label"]); selectors != nil {
+			for name, stream := range all {
+				if !stream.MatchLabels(selectors) {
+					delete(all, name)
+				}
+			}
+		}
+		api.ResponseJSON(w, all)
 		return
 	}
 
@@ -31,7 +40,7 @@ func apiStreams(w http.ResponseWriter, r *http.Request) {
 		if len(cons.Medias) != 0 {
 			cons.WithRequest(r)
 			if err := stream.AddConsumer(cons); err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				http.Error(w, err.Error(), HTTPStatus(err))
 				return
 			}
 
@@ -39,7 +48,7 @@ func apiStreams(w http.ResponseWriter, r *http.Request) {
 
 			stream.RemoveConsumer(cons)
 		} else {
-			api.ResponsePrettyJSON(w, streams[src])
+			api.ResponsePrettyJSON(w, stream)
 		}
 
 	case "PUT":
@@ -53,8 +62,8 @@ func apiStreams(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := app.PatchConfig(name, src, "streams"); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if err := app.PatchState(name, src, "streams"); err != nil {
+			log.Debug().Err(err).Msg("[streams] save state")
 		}
 
 	case "PATCH":
@@ -64,9 +73,20 @@ func apiStreams(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		existed := Get(name) != nil
+
 		// support {input} templates: https://github.com/AlexxIT/go2rtc#module-hass
-		if Patch(name, src) == nil {
+		stream := Patch(name, src)
+		if stream == nil {
 			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+
+		// save only new streams, not aliases and not templates from config
+		if !existed && stream.name == name {
+			if err := app.PatchState(name, src, "streams"); err != nil {
+				log.Debug().Err(err).Msg("[streams] save state")
+			}
 		}
 
 	case "POST":
@@ -85,6 +105,8 @@ func apiStreams(w http.ResponseWriter, r *http.Request) {
 					http.Error(w, err.Error(), http.StatusBadRequest)
 				} else if err = stream.Publish(dst); err != nil {
 					http.Error(w, err.Error(), http.StatusInternalServerError)
+				} else if err = app.PatchState(src, stream.PublishURLs(), "publish"); err != nil {
+					log.Debug().Err(err).Msg("[streams] save state")
 				}
 			} else {
 				http.Error(w, "", http.StatusNotFound)
@@ -94,11 +116,8 @@ func apiStreams(w http.ResponseWriter, r *http.Request) {
 		}
 
 	case "DELETE":
-		delete(streams, src)
-
-		if err := app.PatchConfig(src, nil, "streams"); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		}
+		Delete(src)
+		deleteState(src)
 	}
 }
 
@@ -109,12 +128,12 @@ func apiStreamsDOT(w http.ResponseWriter, r *http.Request) {
 	dot = append(dot, "digraph {\n"...)
 	if query.Has("src") {
 		for _, name := range query["src"] {
-			if stream := streams[name]; stream != nil {
+			if stream := Get(name); stream != nil {
 				dot = AppendDOT(dot, stream)
 			}
 		}
 	} else {
-		for _, stream := range streams {
+		for _, stream := range Streams() {
 			dot = AppendDOT(dot, stream)
 		}
 	}
@@ -0,0 +1,373 @@
+package streams
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/app"
+)
+
+func initAPIv2() {
+	api.HandleRoute(&api.Route{
+		Method: "GET", Path: "streams", Summary: "List streams", Query: []string{"label"},
+	}, apiV2Streams)
+	api.HandleRoute(&api.Route{
+		Method: "POST", Path: "streams", Summary: "Create stream", Status: http.StatusCreated,
+		Body: `{"name":"camera1","sources":["rtsp://192.168.1.123/stream"],"labels":{"gate":"Voskhod-26"}}`,
+	}, apiV2StreamCreate)
+	api.HandleRoute(&api.Route{
+		Method: "GET", Path: "streams/{name}", Summary: "Get stream",
+	}, apiV2Stream)
+	api.HandleRoute(&api.Route{
+		Method: "PUT", Path: "streams/{name}", Summary: "Create or replace stream",
+		Body: `{"sources":["rtsp://192.168.1.123/stream"],"labels":{"gate":"Voskhod-26"}}`,
+	}, apiV2StreamReplace)
+	api.HandleRoute(&api.Route{
+		Method: "DELETE", Path: "streams/{name}", Summary: "Delete stream and close all its connections",
+		Status: http.StatusNoContent,
+	}, apiV2StreamDelete)
+	api.HandleRoute(&api.Route{
+		Method: "GET", Path: "streams/{name}/producers", Summary: "List stream producers",
+	}, apiV2Producers)
+	api.HandleRoute(&api.Route{
+		Method: "GET", Path: "streams/{name}/consumers", Summary: "List stream consumers",
+	}, apiV2StreamConsumers)
+	api.HandleRoute(&api.Route{
+		Method: "GET", Path: "streams/{name}/publish", Summary: "List stream publish targets",
+	}, apiV2Publish)
+	api.HandleRoute(&api.Route{
+		Method: "POST", Path: "streams/{name}/publish", Summary: "Add publish target", Status: http.StatusCreated,
+		Body: `{"url":"rtmp://example.com/live/camera1"}`,
+	}, apiV2PublishCreate)
+	api.HandleRoute(&api.Route{
+		Method: "DELETE", Path: "streams/{name}/publish", Summary: "Stop publish target", Query: []string{"url"},
+		Status: http.StatusNoContent,
+	}, apiV2PublishDelete)
+	api.HandleRoute(&api.Route{
+		Method: "GET", Path: "consumers", Summary: "List consumers of all streams",
+	}, apiV2Consumers)
+	api.HandleRoute(&api.Route{
+		Method: "DELETE", Path: "consumers/{id}", Summary: "Kick consumer", Status: http.StatusNoContent,
+	}, apiV2ConsumerDelete)
+}
+
+// StreamInfo - stream resource of API v2
+type StreamInfo struct {
+	Name      string            `json:"name"`
+	Sources   []string          `json:"sources"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Producers []*Producer       `json:"producers"`
+	Consumers []*ConsumerInfo   `json:"consumers"`
+	Publish   []*publisher      `json:"publish"`
+}
+
+func (s *Stream) info(name string) *StreamInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info := &StreamInfo{
+		Name:      name,
+		Sources:   []string{},
+		Labels:    s.labels,
+		Producers: append([]*Producer{}, s.producers...),
+		Consumers: []*ConsumerInfo{},
+		Publish:   append([]*publisher{}, s.publishers...),
+	}
+	for _, prod := range s.producers {
+		info.Sources = append(info.Sources, prod.url)
+	}
+	for _, cons := range s.consumers {
+		if ci := s.consumerInfo(cons, name); ci != nil {
+			info.Consumers = append(info.Consumers, ci)
+		}
+	}
+	return info
+}
+
+// streamConfig - request body for create and replace
+type streamConfig struct {
+	Name    string            `json:"name,omitempty"`
+	Sources []string          `json:"sources"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+var errForbidden = errors.New("source forbidden for role")
+
+// source - config value for NewStream and for the state file
+func (c *streamConfig) source(role api.Role) (any, error) {
+	if len(c.Sources) == 0 {
+		return nil, errors.New("sources required")
+	}
+
+	sources := make([]any, len(c.Sources))
+	for i, source := range c.Sources {
+		if err := Validate(source); err != nil {
+			return nil, err
+		}
+		if role < api.SourceRole(source) {
+			return nil, errForbidden
+		}
+		sources[i] = source
+	}
+
+	if c.Labels == nil {
+		if len(sources) == 1 {
+			return sources[0], nil
+		}
+		return sources, nil
+	}
+
+	labels := make(map[string]any, len(c.Labels))
+	for k, v := range c.Labels {
+		labels[k] = v
+	}
+	return map[string]any{"url": sources, "labels": labels}, nil
+}
+
+func sourceStatus(err error) int {
+	if err == errForbidden {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+func apiV2Streams(w http.ResponseWriter, r *http.Request) {
+	selectors := parseSelectors(r.URL.Query()["label"])
+
+	infos := []*StreamInfo{}
+	for stream, name := range streamNames() {
+		if stream.MatchLabels(selectors) {
+			infos = append(infos, stream.info(name))
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+
+	api.ResponseStatusJSON(w, http.StatusOK, infos)
+}
+
+func apiV2StreamCreate(w http.ResponseWriter, r *http.Request) {
+	var conf streamConfig
+	if err := api.DecodeJSON(r, &conf); err != nil {
+		api.ResponseError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := ValidateName(conf.Name); err != nil {
+		api.ResponseError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	source, err := conf.source(api.RequestRole(r))
+	if err != nil {
+		api.ResponseError(w, sourceStatus(err), err)
+		return
+	}
+
+	streamsMu.Lock()
+	if _, ok := streams[conf.Name]; ok {
+		streamsMu.Unlock()
+		api.ResponseError(w, http.StatusConflict, api.ErrConflict)
+		return
+	}
+	stream := NewStream(source)
+	stream.setName(conf.Name)
+	streams[conf.Name] = stream
+	streamsMu.Unlock()
+
+	if err = app.PatchState(conf.Name, source, "streams"); err != nil {
+		log.Debug().Err(err).Msg("[streams] save state")
+	}
+
+	api.ResponseStatusJSON(w, http.StatusCreated, stream.info(conf.Name))
+}
+
+func apiV2Stream(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	stream := Get(name)
+	if stream == nil {
+		api.ResponseError(w, http.StatusNotFound, api.ErrNotFound)
+		return
+	}
+
+	api.ResponseStatusJSON(w, http.StatusOK, stream.info(name))
+}
+
+func apiV2StreamReplace(w http.ResponseWriter, r *http.Request) {
+	var conf streamConfig
+	if err := api.DecodeJSON(r, &conf); err != nil {
+		api.ResponseError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	source, err := conf.source(api.RequestRole(r))
+	if err != nil {
+		api.ResponseError(w, sourceStatus(err), err)
+		return
+	}
+
+	name := r.PathValue("name")
+	if err = ValidateName(name); err != nil {
+		api.ResponseError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	streamsMu.Lock()
+	old := deleteLocked(name)
+	stream := NewStream(source)
+	stream.setName(name)
+	streams[name] = stream
+	streamsMu.Unlock()
+
+	// consumers of the old stream should reconnect to the new one
+	if old != nil {
+		old.shutdown()
+	}
+
+	if err = app.PatchState(name, source, "streams"); err != nil {
+		log.Debug().Err(err).Msg("[streams] save state")
+	}
+
+	api.ResponseStatusJSON(w, http.StatusOK, stream.info(name))
+}
+
+func apiV2StreamDelete(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	streamsMu.Lock()
+	_, ok := streams[name]
+	stream := deleteLocked(name)
+	streamsMu.Unlock()
+
+	if !ok {
+		api.ResponseError(w, http.StatusNotFound, api.ErrNotFound)
+		return
+	}
+
+	// close consumers and producers, unlike the old API
+	if stream != nil {
+		stream.shutdown()
+	}
+
+	deleteState(name)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiV2Producers(w http.ResponseWriter, r *http.Request) {
+	stream := Get(r.PathValue("name"))
+	if stream == nil {
+		api.ResponseError(w, http.StatusNotFound, api.ErrNotFound)
+		return
+	}
+
+	stream.mu.Lock()
+	producers := append([]*Producer{}, stream.producers...)
+	stream.mu.Unlock()
+
+	api.ResponseStatusJSON(w, http.StatusOK, producers)
+}
+
+func apiV2StreamConsumers(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	stream := Get(name)
+	if stream == nil {
+		api.ResponseError(w, http.StatusNotFound, api.ErrNotFound)
+		return
+	}
+
+	api.ResponseStatusJSON(w, http.StatusOK, stream.info(name).Consumers)
+}
+
+func apiV2Publish(w http.ResponseWriter, r *http.Request) {
+	stream := Get(r.PathValue("name"))
+	if stream == nil {
+		api.ResponseError(w, http.StatusNotFound, api.ErrNotFound)
+		return
+	}
+
+	stream.mu.Lock()
+	publishers := append([]*publisher{}, stream.publishers...)
+	stream.mu.Unlock()
+
+	api.ResponseStatusJSON(w, http.StatusOK, publishers)
+}
+
+func apiV2PublishCreate(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	stream := Get(name)
+	if stream == nil {
+		api.ResponseError(w, http.StatusNotFound, api.ErrNotFound)
+		return
+	}
+
+	var body struct {
+		URL string `json:"url"`
+	}
+	if err := api.DecodeJSON(r, &body); err != nil {
+		api.ResponseError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate(body.URL); body.URL == "" || err != nil {
+		api.ResponseError(w, http.StatusBadRequest, errors.New("invalid url"))
+		return
+	}
+	if api.RequestRole(r) < api.SourceRole(body.URL) {
+		api.ResponseError(w, http.StatusForbidden, errForbidden)
+		return
+	}
+
+	url := stream.Expand(body.URL)
+
+	// failed target is retried in background, its state is in the publish list
+	if err := stream.Publish(url); err != nil {
+		api.ResponseError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	if err := app.PatchState(name, stream.PublishURLs(), "publish"); err != nil {
+		log.Debug().Err(err).Msg("[streams] save state")
+	}
+
+	api.ResponseStatusJSON(w, http.StatusCreated, map[string]string{"url": url})
+}
+
+func apiV2PublishDelete(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	stream := Get(name)
+	if stream == nil {
+		api.ResponseError(w, http.StatusNotFound, api.ErrNotFound)
+		return
+	}
+
+	if err := stream.Unpublish(r.URL.Query().Get("url")); err != nil {
+		api.ResponseError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if err := app.PatchState(name, stream.PublishURLs(), "publish"); err != nil {
+		log.Debug().Err(err).Msg("[streams] save state")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiV2Consumers(w http.ResponseWriter, r *http.Request) {
+	api.ResponseStatusJSON(w, http.StatusOK, Consumers())
+}
+
+func apiV2ConsumerDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil {
+		api.ResponseError(w, http.StatusBadRequest, errors.New("invalid id"))
+		return
+	}
+
+	if !KickConsumer(uint32(id)) {
+		api.ResponseError(w, http.StatusNotFound, api.ErrNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
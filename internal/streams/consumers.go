@@ -0,0 +1,136 @@
+package streams
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// ConsumerInfo - active consumer session
+type ConsumerInfo struct {
+	ID         uint32    `json:"id"`
+	Stream     string    `json:"stream"`
+	FormatName string    `json:"format_name,omitempty"`
+	Protocol   string    `json:"protocol,omitempty"`
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	Priority   string    `json:"priority"`
+	Started    time.Time `json:"started"`
+	Bytes      int       `json:"bytes_send"`
+	Packets    int       `json:"packets"`
+	Drops      int       `json:"drops"`
+}
+
+// Consumers - all active consumers of all streams
+func Consumers() []*ConsumerInfo {
+	infos := []*ConsumerInfo{}
+	for stream, name := range streamNames() {
+		stream.mu.Lock()
+		for _, cons := range stream.consumers {
+			if info := stream.consumerInfo(cons, name); info != nil {
+				infos = append(infos, info)
+			}
+		}
+		stream.mu.Unlock()
+	}
+	return infos
+}
+
+// KickConsumer - stop consumer session by ID and free its slot, return false if not found
+func KickConsumer(id uint32) bool {
+	for stream := range streamNames() {
+		if cons := stream.findConsumer(id); cons != nil {
+			log.Debug().Msgf("[streams] kick consumer id=%d format=%s", id, formatName(cons))
+			stream.RemoveConsumer(cons)
+			return true
+		}
+	}
+	return false
+}
+
+// streamNames - unique streams with names, aliases use the name of the original stream
+func streamNames() map[*Stream]string {
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+
+	names := make(map[*Stream]string, len(streams))
+	for name, stream := range streams {
+		if stream.name != "" {
+			name = stream.name
+		}
+		names[stream] = name
+	}
+	return names
+}
+
+// consumerInfo - should be called under stream lock
+func (s *Stream) consumerInfo(cons core.Consumer, name string) *ConsumerInfo {
+	conn := connection(cons)
+	if conn == nil {
+		return nil
+	}
+
+	info := &ConsumerInfo{
+		ID:         conn.ID,
+		Stream:     name,
+		FormatName: conn.FormatName,
+		Protocol:   conn.Protocol,
+		RemoteAddr: conn.RemoteAddr,
+		UserAgent:  conn.UserAgent,
+		Priority:   s.priorities[cons].String(),
+		Started:    s.started[cons],
+	}
+
+	for _, sender := range conn.Senders {
+		info.Bytes += sender.Bytes
+		info.Packets += sender.Packets
+		info.Drops += sender.Drops
+	}
+
+	return info
+}
+
+func (s *Stream) findConsumer(id uint32) core.Consumer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, cons := range s.consumers {
+		if conn := connection(cons); conn != nil && conn.ID == id {
+			return cons
+		}
+	}
+	return nil
+}
+
+func apiConsumers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET", "":
+		infos := Consumers()
+		if src := r.URL.Query().Get("src"); src != "" {
+			filtered := []*ConsumerInfo{}
+			for _, info := range infos {
+				if info.Stream == src {
+					filtered = append(filtered, info)
+				}
+			}
+			infos = filtered
+		}
+		api.ResponseJSON(w, infos)
+
+	case "DELETE":
+		id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 32)
+		if err != nil {
+			http.Error(w, "wrong id", http.StatusBadRequest)
+			return
+		}
+		if !KickConsumer(uint32(id)) {
+			http.Error(w, "", http.StatusNotFound)
+		}
+
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,100 @@
+package streams
+
+import (
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/pkg/shell"
+)
+
+const (
+	EventConsumerConnect    Event = "consumer_connect"
+	EventConsumerDisconnect Event = "consumer_disconnect"
+	EventPublishFailed      Event = "publish_failed"
+)
+
+// Message - stream lifecycle event for other modules (webhooks, etc.)
+type Message struct {
+	Event      Event     `json:"event"`
+	Stream     string    `json:"stream"`
+	Time       time.Time `json:"time"`
+	Source     string    `json:"source,omitempty"` // producer or publish URL
+	ID         uint32    `json:"id,omitempty"`     // consumer ID
+	FormatName string    `json:"format_name,omitempty"`
+	Protocol   string    `json:"protocol,omitempty"`
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	User       string    `json:"user,omitempty"`     // username or token subject
+	Token      string    `json:"token,omitempty"`    // access token ID
+	Duration   float64   `json:"duration,omitempty"` // consumer session seconds
+	Error      string    `json:"error,omitempty"`
+}
+
+var eventHandlers []func(msg *Message)
+var eventHandlersMu sync.Mutex
+
+// OnEvent - add stream events handler, handler shouldn't block
+func OnEvent(handler func(msg *Message)) {
+	eventHandlersMu.Lock()
+	eventHandlers = append(eventHandlers, handler)
+	eventHandlersMu.Unlock()
+}
+
+func FireEvent(msg *Message) {
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+
+	// hide camera passwords from events
+	msg.Source = shell.MaskCredentials(msg.Source)
+
+	eventHandlersMu.Lock()
+	handlers := eventHandlers
+	eventHandlersMu.Unlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+}
+
+// setName - stream name for events, aliases use the name of the original stream
+func (s *Stream) setName(name string) {
+	s.name = name
+}
+
+// listenProducer - forward producer events with the current stream name
+// and stop consumers of unavailable source, should be called once for each producer
+func (s *Stream) listenProducer(prod *Producer) {
+	prod.Listen(func(msg any) {
+		switch msg := msg.(type) {
+		case Event:
+			FireEvent(&Message{Event: msg, Stream: s.name, Source: prod.url})
+		case *sourceUnavailable:
+			s.stopUnavailable(msg)
+		}
+	})
+}
+
+// fireConsumerEvent - started and reason only for disconnect
+func (s *Stream) fireConsumerEvent(event Event, cons any, started time.Time, reason error) {
+	msg := &Message{Event: event, Stream: s.name}
+	if conn := connection(cons); conn != nil {
+		msg.ID = conn.ID
+		msg.FormatName = conn.FormatName
+		msg.Protocol = conn.Protocol
+		msg.RemoteAddr = conn.RemoteAddr
+		msg.UserAgent = conn.UserAgent
+		if session := api.GetSession(conn); session != nil {
+			msg.User = session.User
+			msg.Token = session.Token
+		}
+	}
+	if !started.IsZero() {
+		msg.Duration = time.Since(started).Seconds()
+	}
+	if reason != nil {
+		msg.Error = reason.Error()
+	}
+	FireEvent(msg)
+}
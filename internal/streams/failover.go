@@ -0,0 +1,186 @@
+package streams
+
+import (
+	"errors"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// how often check if primary source recovered
+var failbackInterval = 10 * time.Second
+
+// enableFailover - stream sources are alternatives (main and backup),
+// running consumers will be moved to the next healthy source on failure
+// and moved back to the primary source when it recovers
+func (s *Stream) enableFailover() {
+	s.failover = true
+
+	for _, prod := range s.producers {
+		prod.Listen(func(msg any) {
+			if msg == EventProducerOffline {
+				s.failoverFrom(prod)
+			}
+		})
+	}
+}
+
+// failoverFrom - move consumers from failed producer to the first healthy one
+func (s *Stream) failoverFrom(failed *Producer) {
+	s.failoverMu.Lock()
+	defer s.failoverMu.Unlock()
+
+	if !failed.hasConsumers() {
+		return
+	}
+
+	s.mu.Lock()
+	producers := s.producers
+	s.mu.Unlock()
+
+	for _, prod := range producers {
+		if prod == failed || prod.url == "" {
+			continue // skip external and internal producers
+		}
+
+		if err := s.switchProducer(prod, failed); err != nil {
+			log.Debug().Err(err).Msgf("[streams] failover skip url=%s", prod.url)
+			continue
+		}
+
+		log.Info().Msgf("[streams] failover from url=%s to url=%s", failed.url, prod.url)
+
+		if prod != producers[0] {
+			go s.failback()
+		}
+		return
+	}
+
+	log.Warn().Msgf("[streams] failover no healthy sources for url=%s", failed.url)
+}
+
+// failback - move consumers back to the primary producer when it recovers
+func (s *Stream) failback() {
+	if !s.failbackRun.CompareAndSwap(false, true) {
+		return
+	}
+	defer s.failbackRun.Store(false)
+
+	for {
+		time.Sleep(failbackInterval)
+
+		s.failoverMu.Lock()
+
+		primary := s.mainProducer()
+
+		s.mu.Lock()
+		active := s.activeProducer()
+		s.mu.Unlock()
+
+		if primary == nil || active == nil || active == primary {
+			s.failoverMu.Unlock()
+			return
+		}
+
+		err := s.switchProducer(primary, active)
+		s.failoverMu.Unlock()
+
+		if err == nil {
+			log.Info().Msgf("[streams] failback from url=%s to url=%s", active.url, primary.url)
+			return
+		}
+
+		log.Trace().Err(err).Msgf("[streams] failback url=%s", primary.url)
+	}
+}
+
+// switchProducer - move all consumers from src producer tracks to dst producer tracks
+func (s *Stream) switchProducer(dst, src *Producer) error {
+	if err := dst.Dial(); err != nil {
+		return err
+	}
+
+	src.mu.Lock()
+	receivers := src.receivers
+	senders := src.senders
+	src.mu.Unlock()
+
+	var pairs [][2]*core.Receiver
+
+	for _, receiver := range receivers {
+		if len(receiver.Senders()) == 0 {
+			continue
+		}
+
+		track, err := dst.matchTrack(receiver.Codec)
+		if err != nil {
+			// consumers won't be moved, so don't leave an unused connection
+			if !dst.hasConsumers() {
+				dst.stop()
+			}
+			return err
+		}
+
+		pairs = append(pairs, [2]*core.Receiver{track, receiver})
+	}
+
+	if pairs == nil {
+		return errors.New("streams: nothing to move")
+	}
+
+	for _, pair := range pairs {
+		core.MoveNode(&pair[0].Node, &pair[1].Node)
+	}
+
+	// backchannel is optional
+	for _, sender := range senders {
+		for _, media := range dst.GetMedias() {
+			if media.Direction != core.DirectionSendonly {
+				continue
+			}
+			if codec := media.MatchCodec(sender.Codec); codec != nil {
+				_ = dst.AddTrack(media, codec, sender)
+				break
+			}
+		}
+	}
+
+	dst.start()
+	src.stop()
+
+	return nil
+}
+
+// activeProducer - first producer with consumers
+func (s *Stream) activeProducer() *Producer {
+	for _, prod := range s.producers {
+		if prod.hasConsumers() {
+			return prod
+		}
+	}
+	return nil
+}
+
+func (p *Producer) hasConsumers() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, track := range p.receivers {
+		if len(track.Senders()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Producer) matchTrack(codec *core.Codec) (*core.Receiver, error) {
+	for _, media := range p.GetMedias() {
+		if media.Direction != core.DirectionRecvonly {
+			continue
+		}
+		if match := media.MatchCodec(codec); match != nil {
+			return p.GetTrack(media, match)
+		}
+	}
+	return nil, errors.New("streams: codec not matched: " + codec.String())
+}
@@ -0,0 +1,185 @@
+package streams
+
+import (
+	"sync"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/h264"
+	"github.com/AlexxIT/go2rtc/pkg/h265"
+)
+
+// memory protection for sources with very long GOP
+const gopCacheMaxSize = 10 * 1024 * 1024
+
+type gopEntry struct {
+	track  *core.Receiver
+	packet *core.Packet
+}
+
+// gopCache - all producer packets since the last video keyframe,
+// replayed to new consumers, so they don't wait for the next keyframe
+type gopCache struct {
+	entries []gopEntry
+	size    int
+	mu      sync.Mutex
+}
+
+// wrap - cache track packets before sending them to consumers
+func (g *gopCache) wrap(track *core.Receiver) {
+	input := track.Input
+	track.Input = func(packet *core.Packet) {
+		g.mu.Lock()
+		g.add(track, packet)
+		input(packet)
+		g.mu.Unlock()
+	}
+}
+
+func (g *gopCache) add(track *core.Receiver, packet *core.Packet) {
+	if isKeyframe(track.Codec, packet) {
+		g.cut(track, packet.Timestamp)
+	} else if g.entries == nil {
+		return // wait first keyframe
+	}
+
+	g.size += len(packet.Payload)
+	if g.size > gopCacheMaxSize {
+		g.entries = nil
+		g.size = 0
+		return
+	}
+
+	g.entries = append(g.entries, gopEntry{track: track, packet: packet})
+}
+
+// cut - remove all entries before the keyframe access unit, but keep
+// packets with the same timestamp (SPS, PPS and first IFrame parts)
+func (g *gopCache) cut(track *core.Receiver, timestamp uint32) {
+	i := len(g.entries)
+	for i > 0 {
+		entry := g.entries[i-1]
+		if entry.track == track && entry.packet.Timestamp != timestamp {
+			break
+		}
+		i--
+	}
+
+	// nothing to remove if the cache already starts from this keyframe
+	if i > 0 || g.entries == nil {
+		entries := make([]gopEntry, 0, len(g.entries)-i+256)
+		g.entries = append(entries, g.entries[i:]...)
+		g.size = 0
+		for _, entry := range g.entries {
+			g.size += len(entry.packet.Payload)
+		}
+	}
+}
+
+func (g *gopCache) reset() {
+	g.mu.Lock()
+	g.entries = nil
+	g.size = 0
+	g.mu.Unlock()
+}
+
+// addTrack - add consumer track and replay cached packets to the new track childs,
+// live packets for the new childs are queued until replay ends, so packets order
+// won't break and slow consumer doesn't block the producer
+func (g *gopCache) addTrack(track *core.Receiver, add func() error) error {
+	g.mu.Lock()
+
+	childs := track.Childs()
+
+	if err := add(); err != nil {
+		g.mu.Unlock()
+		return err
+	}
+
+	// track.Input is always called under g.mu, so childs Input can be changed here
+	var replays []*gopReplay
+	for _, child := range track.Childs() {
+		if containsNode(childs, child) {
+			continue
+		}
+		replay := &gopReplay{child: child, input: child.Input}
+		child.Input = replay.queue
+		replays = append(replays, replay)
+	}
+
+	var packets []*core.Packet
+	for _, entry := range g.entries {
+		if entry.track == track {
+			packets = append(packets, entry.packet)
+		}
+	}
+
+	g.mu.Unlock()
+
+	for _, replay := range replays {
+		for _, packet := range packets {
+			// send directly to the Sender handler, because Sender buffer may be too small
+			if replay.child.Output != nil {
+				replay.child.Output(packet)
+			} else {
+				replay.input(packet)
+			}
+		}
+	}
+
+	g.mu.Lock()
+	for _, replay := range replays {
+		for _, packet := range replay.queued {
+			replay.input(packet)
+		}
+		replay.child.Input = replay.input
+	}
+	g.mu.Unlock()
+
+	return nil
+}
+
+// gopReplay - live packets of the new child, received during replay
+type gopReplay struct {
+	child  *core.Node
+	input  core.HandlerFunc
+	queued []*core.Packet
+}
+
+func (r *gopReplay) queue(packet *core.Packet) {
+	r.queued = append(r.queued, packet)
+}
+
+// addConsumerTrack - add producer track to consumer with GOP replay if cache enabled
+func (p *Producer) addConsumerTrack(cons core.Consumer, media *core.Media, codec *core.Codec, track *core.Receiver) error {
+	if p.gop == nil {
+		return cons.AddTrack(media, codec, track)
+	}
+	return p.gop.addTrack(track, func() error {
+		return cons.AddTrack(media, codec, track)
+	})
+}
+
+func containsNode(nodes []*core.Node, node *core.Node) bool {
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+func isKeyframe(codec *core.Codec, packet *core.Packet) bool {
+	switch codec.Name {
+	case core.CodecH264:
+		if codec.IsRTP() {
+			return h264.IsKeyframeRTP(packet.Payload)
+		}
+		return h264.IsKeyframe(packet.Payload)
+	case core.CodecH265:
+		if codec.IsRTP() {
+			return h265.IsKeyframeRTP(packet.Payload)
+		}
+		return h265.IsKeyframe(packet.Payload)
+	}
+	return false
+}
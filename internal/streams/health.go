@@ -0,0 +1,133 @@
+package streams
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// StreamHealth - stream summary for orchestrators and monitoring
+type StreamHealth struct {
+	Name      string            `json:"name"`
+	Status    string            `json:"status"` // online, stale, offline, idle
+	Producers []*ProducerHealth `json:"producers"`
+	Consumers int               `json:"consumers"`
+	Keepalive *Health           `json:"keepalive,omitempty"`
+	Recorder  any               `json:"recorder,omitempty"`
+}
+
+type ProducerHealth struct {
+	URL        string      `json:"url"`
+	State      string      `json:"state"`
+	LastPacket *time.Time  `json:"last_packet,omitempty"`
+	Reconnects int         `json:"reconnects,omitempty"`
+	Retry      *RetryState `json:"retry,omitempty"`
+}
+
+// staleTimeout - running producer without packets is stale
+var staleTimeout = 10 * time.Second
+
+var healthHooks []func(h *StreamHealth)
+var healthMu sync.Mutex
+
+// OnHealth - other modules can add info to the stream health (recorder status)
+func OnHealth(f func(h *StreamHealth)) {
+	healthMu.Lock()
+	healthHooks = append(healthHooks, f)
+	healthMu.Unlock()
+}
+
+// trackPacket - should be called under producer lock
+func (p *Producer) trackPacket(track *core.Receiver) {
+	input := track.Input
+	track.Input = func(packet *core.Packet) {
+		p.lastPacket.Store(time.Now().UnixNano())
+		input(packet)
+	}
+}
+
+// health - without main lock, because reconnect holds it while dialing
+func (p *Producer) health() *ProducerHealth {
+	p.infoMu.Lock()
+	defer p.infoMu.Unlock()
+
+	h := &ProducerHealth{
+		URL:        p.url,
+		State:      p.state.String(),
+		Reconnects: p.reconnects,
+	}
+	if p.retryState != nil {
+		retry := *p.retryState
+		h.Retry = &retry
+	}
+	if ts := p.lastPacket.Load(); ts != 0 {
+		t := time.Unix(0, ts)
+		h.LastPacket = &t
+	}
+	return h
+}
+
+func (s *Stream) HealthSummary(name string) *StreamHealth {
+	s.mu.Lock()
+	producers := s.producers
+	consumers := len(s.consumers)
+	s.mu.Unlock()
+
+	h := &StreamHealth{
+		Name:      name,
+		Status:    "idle",
+		Producers: make([]*ProducerHealth, 0, len(producers)),
+		Consumers: consumers,
+		Keepalive: s.Health(),
+	}
+
+	for _, prod := range producers {
+		ph := prod.health()
+		h.Producers = append(h.Producers, ph)
+
+		switch {
+		case ph.State == "start" && ph.Retry == nil:
+			if ph.LastPacket != nil && time.Since(*ph.LastPacket) < staleTimeout {
+				h.Status = "online"
+			} else if h.Status != "online" {
+				h.Status = "stale"
+			}
+		case ph.State == "external" || ph.State == "internal":
+			h.Status = "online"
+		}
+	}
+
+	// consumers are waiting, but all producers are down
+	if h.Status == "idle" && (consumers > 0 || h.Keepalive != nil) {
+		h.Status = "offline"
+	}
+
+	healthMu.Lock()
+	for _, f := range healthHooks {
+		f(h)
+	}
+	healthMu.Unlock()
+
+	return h
+}
+
+func apiHealthStreams(w http.ResponseWriter, r *http.Request) {
+	names := map[string]bool{}
+	for _, name := range r.URL.Query()["src"] {
+		names[name] = true
+	}
+
+	items := []*StreamHealth{}
+	for stream, name := range streamNames() {
+		if len(names) == 0 || names[name] {
+			items = append(items, stream.HealthSummary(name))
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+
+	api.ResponseStatusJSON(w, http.StatusOK, items)
+}
@@ -0,0 +1,212 @@
+package streams
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// how often check keepalive producers
+var keepaliveInterval = 5 * time.Second
+
+// Health - keepalive stream status for API
+type Health struct {
+	Online bool      `json:"online"`
+	Since  time.Time `json:"since"` // time of last status change
+	Error  string    `json:"error,omitempty"`
+}
+
+type keepalive struct {
+	health Health
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+// enableKeepalive - producers will be running without consumers,
+// stream without sources (only push) has nothing to keep alive
+func (s *Stream) enableKeepalive() {
+	if len(s.producers) == 0 {
+		return
+	}
+
+	s.keepalive = &keepalive{done: make(chan struct{})}
+
+	for i, prod := range s.producers {
+		// backup sources don't need to be running
+		if s.failover && i > 0 {
+			break
+		}
+		prod.keepalive = true
+	}
+
+	go s.keepaliveWorker()
+}
+
+func (s *Stream) stopKeepalive() {
+	if s.keepalive == nil {
+		return
+	}
+
+	select {
+	case <-s.keepalive.done:
+		return
+	default:
+		close(s.keepalive.done)
+	}
+
+	s.mu.Lock()
+	for _, prod := range s.producers {
+		prod.keepalive = false
+	}
+	s.mu.Unlock()
+
+	s.stopProducers()
+}
+
+// Health - nil if keepalive is not enabled for the stream
+func (s *Stream) Health() *Health {
+	if s.keepalive == nil {
+		return nil
+	}
+
+	s.keepalive.mu.Lock()
+	defer s.keepalive.mu.Unlock()
+
+	health := s.keepalive.health
+	return &health
+}
+
+func (s *Stream) keepaliveWorker() {
+	// wait for all source handlers registration, same as for publish
+	delay := time.Second
+	retry := 0
+
+	for {
+		select {
+		case <-time.After(delay):
+		case <-s.keepalive.done:
+			return
+		}
+
+		err := s.keepaliveCheck()
+		s.setHealth(err)
+
+		if err == nil {
+			retry = 0
+			delay = keepaliveInterval
+			continue
+		}
+
+		// producer reconnects itself, so retry only dial errors
+		policy := defaultRetry
+		if prod := s.mainProducer(); prod != nil && prod.retry != nil {
+			policy = *prod.retry
+		}
+		delay = max(policy.Delay(retry), keepaliveInterval)
+		retry++
+	}
+}
+
+// keepaliveCheck - start stopped producers and return last error
+func (s *Stream) keepaliveCheck() (err error) {
+	var producers []*Producer
+
+	s.mu.Lock()
+	for _, prod := range s.producers {
+		if prod.keepalive {
+			producers = append(producers, prod)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, prod := range producers {
+		prod.mu.Lock()
+		state := prod.state
+		retry := prod.retryState
+		prod.mu.Unlock()
+
+		switch state {
+		case stateStart:
+			if retry != nil {
+				err = errors.New(retry.Error)
+			}
+		case stateNone:
+			if err1 := prod.keepaliveStart(); err1 != nil {
+				log.Debug().Err(err1).Msgf("[streams] keepalive url=%s", prod.url)
+				err = err1
+			}
+		}
+	}
+
+	return
+}
+
+func (s *Stream) setHealth(err error) {
+	health := Health{Online: err == nil}
+	if err != nil {
+		health.Error = err.Error()
+	}
+
+	k := s.keepalive
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.health.Online != health.Online || k.health.Since.IsZero() {
+		health.Since = time.Now()
+
+		var url string
+		if prod := s.mainProducer(); prod != nil {
+			url = prod.url
+		}
+		if health.Online {
+			log.Debug().Msgf("[streams] keepalive online url=%s", url)
+		} else {
+			log.Warn().Err(err).Msgf("[streams] keepalive offline url=%s", url)
+		}
+	} else {
+		health.Since = k.health.Since
+	}
+
+	k.health = health
+}
+
+// mainProducer - first producer of the stream or nil
+func (s *Stream) mainProducer() *Producer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.producers) == 0 {
+		return nil
+	}
+	return s.producers[0]
+}
+
+// keepaliveStart - dial producer and start all recvonly tracks,
+// because some sources (RTSP) can't add tracks after start
+func (p *Producer) keepaliveStart() error {
+	if err := p.Dial(); err != nil {
+		return err
+	}
+
+	for _, media := range p.GetMedias() {
+		if media.Direction != core.DirectionRecvonly || len(media.Codecs) == 0 {
+			continue
+		}
+		if _, err := p.GetTrack(media, media.Codecs[0]); err != nil {
+			log.Debug().Err(err).Msgf("[streams] keepalive can't get track url=%s", p.url)
+		}
+	}
+
+	p.start()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state != stateStart {
+		p.close()
+		return errors.New("streams: keepalive can't start producer")
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package streams
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// parseLabels - labels from stream config, device_name is also a label for compatibility
+func parseLabels(conf map[string]any) map[string]string {
+	var labels map[string]string
+
+	if items, ok := conf["labels"].(map[string]any); ok {
+		labels = make(map[string]string, len(items))
+		for k, v := range items {
+			if v != nil {
+				labels[k] = fmt.Sprint(v)
+			}
+		}
+	}
+
+	if name, ok := conf["device_name"].(string); ok {
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		if _, ok = labels["device_name"]; !ok {
+			labels["device_name"] = name
+		}
+	}
+
+	return labels
+}
+
+// Labels - stream labels from config, shouldn't be changed
+func (s *Stream) Labels() map[string]string {
+	return s.labels
+}
+
+func (s *Stream) Label(key string) string {
+	return s.labels[key]
+}
+
+// MatchLabels - all selectors should match: "key:value" or "key" for any value
+func (s *Stream) MatchLabels(selectors []string) bool {
+	for _, selector := range selectors {
+		key, value, ok := strings.Cut(selector, ":")
+		v, exists := s.labels[key]
+		if !exists || ok && v != value {
+			return false
+		}
+	}
+	return true
+}
+
+var labelTemplate = regexp.MustCompile(`\{labels\.([\w.-]+)}`)
+
+// Expand - replace {name} and {labels.key} in the template, for publish URLs and other modules
+func (s *Stream) Expand(template string) string {
+	template = strings.ReplaceAll(template, "{name}", s.name)
+	return labelTemplate.ReplaceAllStringFunc(template, func(m string) string {
+		return s.labels[m[8:len(m)-1]]
+	})
+}
+
+// parseSelectors - label query params: ?label=building:A&label=entrance:1 or ?label=building:A,entrance:1
+func parseSelectors(values []string) (selectors []string) {
+	for _, value := range values {
+		for _, selector := range strings.Split(value, ",") {
+			if selector != "" {
+				selectors = append(selectors, selector)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,191 @@
+package streams
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// Priority - consumer with higher priority can evict consumer with lower priority
+type Priority byte
+
+const (
+	PriorityViewer Priority = iota
+	PriorityOperator
+	PriorityRecorder
+)
+
+func ParsePriority(s string) Priority {
+	switch s {
+	case "operator":
+		return PriorityOperator
+	case "recorder":
+		return PriorityRecorder
+	}
+	return PriorityViewer
+}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityOperator:
+		return "operator"
+	case PriorityRecorder:
+		return "recorder"
+	}
+	return "viewer"
+}
+
+var ErrConsumersLimit = errors.New("streams: reached limit of consumers")
+
+// HTTPStatus - status code for AddConsumer error
+func HTTPStatus(err error) int {
+	if errors.Is(err, ErrConsumersLimit) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// consumers limits from config
+var (
+	consumersLimit      = 3                     // per stream, 0 - unlimited
+	consumersFormats    = map[string]int{}      // per stream per format
+	consumersPriorities = map[string]Priority{} // per format
+)
+
+func initLimits() {
+	var cfg struct {
+		Mod struct {
+			Limit      *int              `yaml:"limit"`
+			Formats    map[string]int    `yaml:"formats"`
+			Priorities map[string]string `yaml:"priority"`
+
+			Sessions sessionConfigs `yaml:"sessions"`
+		} `yaml:"consumers"`
+	}
+
+	app.LoadConfig(&cfg)
+
+	if cfg.Mod.Limit != nil {
+		consumersLimit = *cfg.Mod.Limit
+	}
+
+	if cfg.Mod.Formats != nil {
+		consumersFormats = cfg.Mod.Formats
+	}
+
+	for format, s := range cfg.Mod.Priorities {
+		consumersPriorities[format] = ParsePriority(s)
+	}
+
+	if cfg.Mod.Sessions != nil {
+		consumersSessions = cfg.Mod.Sessions
+	}
+}
+
+func connection(v any) *core.Connection {
+	if c, ok := v.(interface{ GetConnection() *core.Connection }); ok {
+		return c.GetConnection()
+	}
+	return nil
+}
+
+func formatName(v any) string {
+	if c := connection(v); c != nil {
+		return c.FormatName
+	}
+	return ""
+}
+
+// checkLimits - evict lowest priority consumer or return error if limits reached,
+// on success reserve a slot for the consumer, so concurrent consumers can't exceed
+// limits while dialing producers, slot should be released with releaseLocked
+func (s *Stream) checkLimits(cons core.Consumer, priority Priority) error {
+	format := formatName(cons)
+
+	for {
+		s.mu.Lock()
+		limit, victim := s.findVictim(format, priority)
+		if limit == 0 {
+			s.reserved = append(s.reserved, cons)
+			s.mu.Unlock()
+			return nil
+		}
+		victimPriority := s.priorities[victim]
+		s.mu.Unlock()
+
+		if victim == nil {
+			return fmt.Errorf("%w (<= %d)", ErrConsumersLimit, limit)
+		}
+
+		log.Debug().Msgf("[streams] evict consumer format=%s priority=%s", formatName(victim), victimPriority)
+
+		s.RemoveConsumer(victim)
+	}
+}
+
+// releaseLocked - remove slot reservation of the consumer, should be called under s.mu
+func (s *Stream) releaseLocked(cons core.Consumer) {
+	for i, reserved := range s.reserved {
+		if reserved == cons {
+			s.reserved = append(s.reserved[:i], s.reserved[i+1:]...)
+			return
+		}
+	}
+}
+
+// findVictim - return reached limit and the oldest consumer with lowest priority,
+// reserved slots are counted, but can't be evicted
+func (s *Stream) findVictim(format string, priority Priority) (limit int, victim core.Consumer) {
+	limit = consumersLimit
+	if s.limit != 0 {
+		limit = s.limit
+	}
+
+	if limit > 0 && len(s.consumers)+len(s.reserved) >= limit {
+		return limit, s.lowestConsumer("", priority)
+	}
+
+	if limit = consumersFormats[format]; limit > 0 {
+		var n int
+		for _, consumer := range s.consumers {
+			if formatName(consumer) == format {
+				n++
+			}
+		}
+		for _, consumer := range s.reserved {
+			if formatName(consumer) == format {
+				n++
+			}
+		}
+		if n >= limit {
+			return limit, s.lowestConsumer(format, priority)
+		}
+	}
+
+	return 0, nil
+}
+
+func (s *Stream) lowestConsumer(format string, priority Priority) (victim core.Consumer) {
+	for _, consumer := range s.consumers {
+		if format != "" && formatName(consumer) != format {
+			continue
+		}
+		if p := s.priorities[consumer]; p < priority {
+			if victim == nil || p < s.priorities[victim] {
+				victim = consumer
+			}
+		}
+	}
+	return
+}
+
+func sessionLimits(cons core.Consumer) *api.Limits {
+	if c := connection(cons); c != nil {
+		return api.SessionLimits(c)
+	}
+	return nil
+}
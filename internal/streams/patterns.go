@@ -0,0 +1,164 @@
+package streams
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// pattern - stream config with placeholders in the name: "panel_{id}: rtsp://10.0.{id}.2/stream"
+type pattern struct {
+	re     *regexp.Regexp
+	keys   []string
+	source any
+	idle   time.Duration
+}
+
+// delete pattern streams without consumers after this time
+var patternIdle = time.Minute
+
+// how often check pattern streams
+var patternsInterval = 10 * time.Second
+
+var patterns []*pattern
+var patternStreams = map[string]*patternStream{}
+
+type patternStream struct {
+	stream *Stream
+	idle   time.Duration
+}
+
+var placeholder = regexp.MustCompile(`\{(\w+)}`)
+
+func isPattern(name string) bool {
+	return placeholder.MatchString(name)
+}
+
+func addPattern(name string, source any) {
+	p := &pattern{source: source, idle: patternIdle}
+
+	// placeholder values are inserted into the sources, so allow only safe symbols
+	expr := "^"
+	for {
+		loc := placeholder.FindStringSubmatchIndex(name)
+		if loc == nil {
+			break
+		}
+		expr += regexp.QuoteMeta(name[:loc[0]]) + `([\w.-]+)`
+		p.keys = append(p.keys, name[loc[2]:loc[3]])
+		name = name[loc[1]:]
+	}
+	p.re = regexp.MustCompile(expr + regexp.QuoteMeta(name) + "$")
+
+	if conf, ok := source.(map[string]any); ok {
+		if d, ok := parseDuration(conf["idle_timeout"]); ok {
+			p.idle = d
+		}
+	}
+
+	patterns = append(patterns, p)
+}
+
+// match - return source with placeholder values or nil
+func (p *pattern) match(name string) any {
+	values := p.re.FindStringSubmatch(name)
+	if values == nil {
+		return nil
+	}
+
+	pairs := make([]string, 0, 2*len(p.keys))
+	for i, key := range p.keys {
+		pairs = append(pairs, "{"+key+"}", values[i+1])
+	}
+
+	return expand(p.source, strings.NewReplacer(pairs...))
+}
+
+func expand(source any, r *strings.Replacer) any {
+	switch source := source.(type) {
+	case string:
+		return r.Replace(source)
+	case []any:
+		items := make([]any, len(source))
+		for i, item := range source {
+			items[i] = expand(item, r)
+		}
+		return items
+	case map[string]any:
+		items := make(map[string]any, len(source))
+		for k, v := range source {
+			items[k] = expand(v, r)
+		}
+		return items
+	}
+	return source
+}
+
+// getLocked - existing stream or new stream from pattern, should be called under streamsMu lock
+func getLocked(name string) *Stream {
+	if stream, ok := streams[name]; ok {
+		return stream
+	}
+
+	for _, p := range patterns {
+		source := p.match(name)
+		if source == nil {
+			continue
+		}
+
+		log.Debug().Msgf("[streams] create stream from pattern name=%s", name)
+
+		stream := NewStream(source)
+		stream.setName(name)
+		streams[name] = stream
+		patternStreams[name] = &patternStream{stream: stream, idle: p.idle}
+		return stream
+	}
+
+	return nil
+}
+
+func patternsWorker() {
+	idle := map[*Stream]time.Time{}
+
+	for {
+		time.Sleep(patternsInterval)
+
+		now := time.Now()
+
+		var deleted []*Stream
+
+		streamsMu.Lock()
+		for name, item := range patternStreams {
+			stream := item.stream
+
+			// stream was deleted or replaced by API
+			if streams[name] != stream {
+				delete(patternStreams, name)
+				delete(idle, stream)
+				continue
+			}
+
+			if stream.ConsumersCount() > 0 || stream.pending.Load() > 0 {
+				delete(idle, stream)
+				continue
+			}
+
+			if since, ok := idle[stream]; !ok {
+				idle[stream] = now
+			} else if now.Sub(since) >= item.idle {
+				log.Debug().Msgf("[streams] delete idle pattern stream name=%s", name)
+				if s := deleteLocked(name); s != nil {
+					deleted = append(deleted, s)
+				}
+				delete(patternStreams, name)
+				delete(idle, stream)
+			}
+		}
+		streamsMu.Unlock()
+
+		for _, stream := range deleted {
+			stream.stopKeepalive()
+		}
+	}
+}
@@ -112,6 +112,11 @@ func (s *Stream) AddInternalProducer(conn core.Producer) {
 func (s *Stream) AddInternalConsumer(conn core.Consumer) {
 	s.mu.Lock()
 	s.consumers = append(s.consumers, conn)
+	if s.priorities == nil {
+		s.priorities = map[core.Consumer]Priority{}
+	}
+	// internal consumers can't be evicted
+	s.priorities[conn] = PriorityRecorder
 	s.mu.Unlock()
 }
 
@@ -120,9 +125,11 @@ func (s *Stream) RemoveInternalConsumer(conn core.Consumer) {
 	for i, consumer := range s.consumers {
 		if consumer == conn {
 			s.consumers = append(s.consumers[:i], s.consumers[i+1:]...)
+			s.closeStats(conn)
 			break
 		}
 	}
+	delete(s.priorities, conn)
 	s.mu.Unlock()
 }
 
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/AlexxIT/go2rtc/pkg/core"
@@ -34,8 +35,34 @@ type Producer struct {
 	state    state
 	mu       sync.Mutex
 	workerID int
+
+	// infoMu - state, conn, retryState and reconnects for API, writes also under mu,
+	// because reconnect holds mu while dialing
+	infoMu sync.Mutex
+
+	retry      *RetryPolicy // nil - use default policy
+	retryState *RetryState  // nil - not retrying
+	reconnects int
+	keepalive  bool      // don't stop without consumers
+	gop        *gopCache // nil - cache disabled
+
+	watchdogConf *Watchdog // nil - use default watchdog
+	watchdogID   int
+	watches      map[*core.Receiver]*trackWatch
+
+	lastPacket atomic.Int64 // unix nanoseconds, for health
+	status     Event        // last online or offline event
 }
 
+// Event - Producer events for Listener
+type Event string
+
+const (
+	EventProducerOffline Event = "producer_offline" // dial or first reconnect failed, or producer stopped
+	EventProducerOnline  Event = "producer_online"  // producer started or reconnected after fail
+	EventProducerFrozen  Event = "producer_frozen"  // watchdog forced reconnect
+)
+
 const SourceTemplate = "{input}"
 
 func NewProducer(source string) *Producer {
@@ -61,11 +88,12 @@ func (p *Producer) Dial() error {
 	if p.state == stateNone {
 		conn, err := GetProducer(p.url)
 		if err != nil {
+			p.setStatus(EventProducerOffline)
 			return err
 		}
 
-		p.conn = conn
-		p.state = stateMedias
+		p.setConn(conn, nil)
+		p.setState(stateMedias)
 	}
 
 	return nil
@@ -103,8 +131,15 @@ func (p *Producer) GetTrack(media *core.Media, codec *core.Codec) (*core.Receive
 
 	p.receivers = append(p.receivers, track)
 
+	if p.gop != nil {
+		p.gop.wrap(track)
+	}
+
+	p.watchTrack(track)
+	p.trackPacket(track)
+
 	if p.state == stateMedias {
-		p.state = stateTracks
+		p.setState(stateTracks)
 	}
 
 	return track, nil
@@ -125,17 +160,40 @@ func (p *Producer) AddTrack(media *core.Media, codec *core.Codec, track *core.Re
 	p.senders = append(p.senders, track)
 
 	if p.state == stateMedias {
-		p.state = stateTracks
+		p.setState(stateTracks)
 	}
 
 	return nil
 }
 
 func (p *Producer) MarshalJSON() ([]byte, error) {
-	if conn := p.conn; conn != nil {
+	// without main lock, because reconnect holds it while dialing
+	p.infoMu.Lock()
+	conn := p.conn
+	retry := p.retryState
+	p.infoMu.Unlock()
+
+	if conn != nil && retry == nil {
 		return json.Marshal(conn)
 	}
-	info := map[string]string{"url": p.url}
+
+	info := map[string]any{"url": p.url}
+
+	if conn != nil {
+		// add retry state to the connection info
+		b, err := json.Marshal(conn)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(b, &info); err != nil {
+			return nil, err
+		}
+	}
+
+	if retry != nil {
+		info["retry"] = retry
+	}
+
 	return json.Marshal(info)
 }
 
@@ -151,10 +209,14 @@ func (p *Producer) start() {
 
 	log.Debug().Msgf("[streams] start producer url=%s", p.url)
 
-	p.state = stateStart
+	p.setState(stateStart)
 	p.workerID++
 
+	p.setStatus(EventProducerOnline)
+
 	go p.worker(p.conn, p.workerID)
+
+	p.watchdog()
 }
 
 func (p *Producer) worker(conn core.Producer, workerID int) {
@@ -184,25 +246,41 @@ func (p *Producer) reconnect(workerID, retry int) {
 
 	log.Debug().Msgf("[streams] retry=%d to url=%s", retry, p.url)
 
+	p.infoMu.Lock()
+	p.reconnects++
+	p.infoMu.Unlock()
+
 	conn, err := GetProducer(p.url)
 	if err != nil {
 		log.Debug().Msgf("[streams] producer=%s", err)
 
-		timeout := time.Minute
-		if retry < 5 {
-			timeout = time.Second
-		} else if retry < 10 {
-			timeout = time.Second * 5
-		} else if retry < 20 {
-			timeout = time.Second * 10
+		p.setStatus(EventProducerOffline)
+
+		policy := p.retryPolicy()
+		if policy.Exceeded(retry + 1) {
+			log.Warn().Msgf("[streams] stop reconnect after %d attempts url=%s", retry+1, p.url)
+			p.setRetry(&RetryState{Attempt: retry + 1, Error: err.Error()})
+			// consumers won't get packets from the closed connection, so the stream should stop them
+			nodes := p.nodes()
+			p.close()
+			go p.Fire(&sourceUnavailable{nodes: nodes})
+			return
 		}
 
+		timeout := policy.Delay(retry)
+		p.setRetry(&RetryState{Attempt: retry + 1, Next: time.Now().Add(timeout), Error: err.Error()})
+
 		time.AfterFunc(timeout, func() {
 			p.reconnect(workerID, retry+1)
 		})
 		return
 	}
 
+	// new connection has new timestamps
+	if p.gop != nil {
+		p.gop.reset()
+	}
+
 	for _, media := range conn.GetMedias() {
 		switch media.Direction {
 		case core.DirectionRecvonly:
@@ -217,6 +295,14 @@ func (p *Producer) reconnect(workerID, retry int) {
 					continue
 				}
 
+				if p.gop != nil {
+					p.gop.wrap(track)
+				}
+
+				delete(p.watches, receiver)
+				p.watchTrack(track)
+				p.trackPacket(track)
+
 				receiver.Replace(track)
 				p.receivers[i] = track
 				break
@@ -237,7 +323,9 @@ func (p *Producer) reconnect(workerID, retry int) {
 	// stop previous connection after moving tracks (fix ghost exec/ffmpeg)
 	_ = p.conn.Stop()
 	// swap connections
-	p.conn = conn
+	p.setConn(conn, nil)
+
+	p.setStatus(EventProducerOnline)
 
 	go p.worker(conn, workerID)
 }
@@ -259,12 +347,62 @@ func (p *Producer) stop() {
 
 	log.Debug().Msgf("[streams] stop producer url=%s", p.url)
 
+	p.setStatus(EventProducerOffline)
+	p.close()
+}
+
+func (p *Producer) close() {
 	if p.conn != nil {
 		_ = p.conn.Stop()
-		p.conn = nil
+		p.setConn(nil, p.retryState)
+	}
+
+	if p.gop != nil {
+		p.gop.reset()
 	}
 
-	p.state = stateNone
+	p.setState(stateNone)
 	p.receivers = nil
+	p.watches = nil
 	p.senders = nil
 }
+
+// setConn - should be called under producer lock
+func (p *Producer) setConn(conn core.Producer, retry *RetryState) {
+	p.infoMu.Lock()
+	p.conn = conn
+	p.retryState = retry
+	p.infoMu.Unlock()
+}
+
+// setState - should be called under producer lock
+func (p *Producer) setState(state state) {
+	p.infoMu.Lock()
+	p.state = state
+	p.infoMu.Unlock()
+}
+
+// setRetry - should be called under producer lock
+func (p *Producer) setRetry(retry *RetryState) {
+	p.infoMu.Lock()
+	p.retryState = retry
+	p.infoMu.Unlock()
+}
+
+// setStatus - fire online and offline events only on change, should be called under producer lock
+func (p *Producer) setStatus(event Event) {
+	if p.status == event {
+		return
+	}
+	p.status = event
+
+	// async, because listeners may want to stop this producer
+	go p.Fire(event)
+}
+
+func (p *Producer) retryPolicy() *RetryPolicy {
+	if p.retry != nil {
+		return p.retry
+	}
+	return &defaultRetry
+}
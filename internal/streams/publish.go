@@ -1,29 +1,232 @@
 package streams
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+type publisher struct {
+	url     string
+	retry   RetryPolicy
+	state   *RetryState
+	cons    core.Consumer // current consumer
+	stopped bool
+	done    chan struct{} // interrupts retry delay
+	mu      sync.Mutex
+}
+
+// stop - worker exits after the current session or the retry delay,
+// returns the current consumer
+func (p *publisher) stop() core.Consumer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.stopped {
+		p.stopped = true
+		close(p.done)
+	}
+	return p.cons
+}
+
+func (p *publisher) MarshalJSON() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info := map[string]any{"url": p.url}
+	if p.state != nil {
+		info["retry"] = p.state
+	}
+	return json.Marshal(info)
+}
+
+// PublishURLs - all publish targets of the stream, for saving state
+func (s *Stream) PublishURLs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	urls := make([]string, 0, len(s.publishers))
+	for _, pub := range s.publishers {
+		urls = append(urls, pub.url)
+	}
+	return urls
+}
 
 func (s *Stream) Publish(url string) error {
-	cons, run, err := GetConsumer(url)
-	if err != nil {
+	return s.PublishWithRetry(url, defaultRetry)
+}
+
+// PublishWithRetry - first attempt is synchronous, next attempts are in background
+// according to the retry policy. Returns error only if the target won't be retried,
+// existing target with the same url is not added twice.
+func (s *Stream) PublishWithRetry(url string, retry RetryPolicy) error {
+	// support templates: rtmp://example.com/live/{labels.building}-{name}
+	url = s.Expand(url)
+
+	// check destination before adding to publishers list
+	if _, _, err := GetConsumer(url); err != nil {
 		return err
 	}
 
-	if err = s.AddConsumer(cons); err != nil {
-		return err
+	pub := &publisher{url: url, retry: retry, done: make(chan struct{})}
+
+	s.mu.Lock()
+	for _, p := range s.publishers {
+		if p.url == url {
+			s.mu.Unlock()
+			return nil
+		}
 	}
+	s.publishers = append(s.publishers, pub)
+	s.mu.Unlock()
 
-	go func() {
-		run()
-		s.RemoveConsumer(cons)
+	cons, run, err := s.publishAttempt(pub)
+	if err != nil {
+		if retry.Exceeded(1) {
+			s.removePublisher(pub)
+			return err
+		}
+		log.Warn().Err(err).Msgf("[streams] publish failed, retry in background url=%s", url)
+	}
 
-		// TODO: more smart retry
-		time.Sleep(5 * time.Second)
-		_ = s.Publish(url)
-	}()
+	go s.publishWorker(pub, cons, run, err)
 
 	return nil
 }
 
+func (s *Stream) publishAttempt(pub *publisher) (core.Consumer, func(), error) {
+	cons, run, err := GetConsumer(pub.url)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err = s.AddConsumerWithPriority(cons, PriorityOperator); err != nil {
+		return nil, nil, err
+	}
+
+	return cons, run, nil
+}
+
+func (s *Stream) publishWorker(pub *publisher, cons core.Consumer, run func(), err error) {
+	for attempt := 0; ; attempt++ {
+		if err == nil {
+			pub.mu.Lock()
+			pub.state = nil
+			pub.cons = cons
+			stopped := pub.stopped
+			pub.mu.Unlock()
+
+			if stopped {
+				s.RemoveConsumer(cons)
+				return
+			}
+
+			ts := time.Now()
+			run()
+			s.RemoveConsumer(cons)
+
+			pub.mu.Lock()
+			pub.cons = nil
+			stopped = pub.stopped
+			pub.mu.Unlock()
+
+			if stopped {
+				return
+			}
+
+			// long session was successful, so start counting from the beginning
+			if time.Since(ts) > retryResetTimeout {
+				attempt = 0
+			}
+
+			err = errors.New("streams: publish stopped")
+		}
+
+		if app.ShuttingDown() {
+			return
+		}
+
+		FireEvent(&Message{Event: EventPublishFailed, Stream: s.name, Source: pub.url, Error: err.Error()})
+
+		if pub.retry.Exceeded(attempt + 1) {
+			log.Warn().Err(err).Msgf("[streams] stop publish after %d attempts url=%s", attempt+1, pub.url)
+			s.removePublisher(pub)
+			return
+		}
+
+		delay := pub.retry.Delay(attempt)
+
+		pub.mu.Lock()
+		pub.state = &RetryState{Attempt: attempt + 1, Next: time.Now().Add(delay), Error: err.Error()}
+		pub.mu.Unlock()
+
+		log.Debug().Err(err).Msgf("[streams] retry=%d publish in %s url=%s", attempt+1, delay, pub.url)
+
+		select {
+		case <-time.After(delay):
+		case <-pub.done:
+			return
+		}
+
+		cons, run, err = s.publishAttempt(pub)
+	}
+}
+
+// Unpublish - stop publish to the url and remove it from the publish targets
+func (s *Stream) Unpublish(url string) error {
+	s.mu.Lock()
+	var pub *publisher
+	for _, p := range s.publishers {
+		if p.url == url {
+			pub = p
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if pub == nil {
+		return errors.New("streams: publish target not found: " + url)
+	}
+
+	s.removePublisher(pub)
+
+	if cons := pub.stop(); cons != nil {
+		s.RemoveConsumer(cons)
+	}
+
+	return nil
+}
+
+// stopPublishers - stop publish workers of deleted or replaced stream,
+// publish targets stay in the list for the API
+func (s *Stream) stopPublishers() {
+	s.mu.Lock()
+	publishers := s.publishers
+	s.mu.Unlock()
+
+	for _, pub := range publishers {
+		if cons := pub.stop(); cons != nil {
+			s.RemoveConsumer(cons)
+		}
+	}
+}
+
+func (s *Stream) removePublisher(pub *publisher) {
+	s.mu.Lock()
+	for i, p := range s.publishers {
+		if p == pub {
+			s.publishers = append(s.publishers[:i], s.publishers[i+1:]...)
+			break
+		}
+	}
+	s.mu.Unlock()
+}
+
+// Publish - support destination as string, list or map with url and retry policy
 func Publish(stream *Stream, destination any) {
 	switch v := destination.(type) {
 	case string:
@@ -34,5 +237,11 @@ func Publish(stream *Stream, destination any) {
 		for _, v := range v {
 			Publish(stream, v)
 		}
+	case map[string]any:
+		url, _ := v["url"].(string)
+		conf, _ := v["retry"].(map[string]any)
+		if err := stream.PublishWithRetry(url, ParseRetry(conf, defaultRetry)); err != nil {
+			log.Error().Err(err).Caller().Send()
+		}
 	}
 }
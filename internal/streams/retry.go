@@ -0,0 +1,161 @@
+package streams
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+var ErrSourceUnavailable = errors.New("streams: source unavailable")
+
+// RetryPolicy - exponential backoff with jitter
+type RetryPolicy struct {
+	InitialDelay time.Duration `yaml:"initial_delay" json:"initial_delay"`
+	MaxDelay     time.Duration `yaml:"max_delay" json:"max_delay"`
+	Jitter       float64       `yaml:"jitter" json:"jitter"`             // from 0 to 1
+	MaxAttempts  int           `yaml:"max_attempts" json:"max_attempts"` // 0 - unlimited
+}
+
+// RetryState - current retry info for API
+type RetryState struct {
+	Attempt int       `json:"attempt"`
+	Next    time.Time `json:"next,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+var defaultRetry = RetryPolicy{
+	InitialDelay: time.Second,
+	MaxDelay:     time.Minute,
+	Jitter:       0.2,
+}
+
+const minRetryDelay = 100 * time.Millisecond
+
+// reset attempts counter if publish session was running longer than this
+const retryResetTimeout = time.Minute
+
+func initRetry() {
+	var cfg struct {
+		Retry map[string]any `yaml:"retry"`
+	}
+
+	app.LoadConfig(&cfg)
+
+	defaultRetry = ParseRetry(cfg.Retry, defaultRetry)
+}
+
+// ParseRetry - parse retry policy from config map with defaults from base
+func ParseRetry(conf map[string]any, base RetryPolicy) RetryPolicy {
+	if conf == nil {
+		return base
+	}
+
+	if d, ok := parseDuration(conf["initial_delay"]); ok {
+		// zero delay will retry in a hot loop
+		base.InitialDelay = max(d, minRetryDelay)
+	}
+	if d, ok := parseDuration(conf["max_delay"]); ok {
+		base.MaxDelay = d
+	}
+	switch v := conf["jitter"].(type) {
+	case float64:
+		base.Jitter = v
+	case int:
+		base.Jitter = float64(v)
+	}
+	if v, ok := conf["max_attempts"].(int); ok {
+		base.MaxAttempts = v
+	}
+
+	return base
+}
+
+func parseDuration(v any) (time.Duration, bool) {
+	switch v := v.(type) {
+	case string:
+		d, err := time.ParseDuration(v)
+		return d, err == nil
+	case int:
+		return time.Duration(v) * time.Second, true
+	}
+	return 0, false
+}
+
+// Delay - timeout before attempt (starting from zero)
+func (r *RetryPolicy) Delay(attempt int) time.Duration {
+	d := r.InitialDelay
+	for i := 0; i < attempt && i < 32 && (r.MaxDelay == 0 || d < r.MaxDelay); i++ {
+		d *= 2
+	}
+	if r.MaxDelay > 0 && d > r.MaxDelay {
+		d = r.MaxDelay
+	}
+
+	if r.Jitter > 0 {
+		d += time.Duration(float64(d) * r.Jitter * (rand.Float64()*2 - 1))
+	}
+
+	return d
+}
+
+// Exceeded - no more attempts allowed
+func (r *RetryPolicy) Exceeded(attempt int) bool {
+	return r.MaxAttempts > 0 && attempt >= r.MaxAttempts
+}
+
+// sourceUnavailable - producer message after the last reconnect attempt
+type sourceUnavailable struct {
+	nodes map[*core.Node]bool
+}
+
+// nodes - receivers and all their childs, should be called under producer lock
+func (p *Producer) nodes() map[*core.Node]bool {
+	nodes := map[*core.Node]bool{}
+
+	var walk func(node *core.Node)
+	walk = func(node *core.Node) {
+		if nodes[node] {
+			return
+		}
+		nodes[node] = true
+		for _, child := range node.Childs() {
+			walk(child)
+		}
+	}
+
+	for _, receiver := range p.receivers {
+		walk(&receiver.Node)
+	}
+	return nodes
+}
+
+// stopUnavailable - stop consumers of the producer without more reconnect attempts,
+// so they free their slots and next consumer will dial the source again
+func (s *Stream) stopUnavailable(msg *sourceUnavailable) {
+	s.mu.Lock()
+	consumers := slices.Clone(s.consumers)
+	single := len(s.producers) == 1
+	s.mu.Unlock()
+
+	for _, cons := range consumers {
+		conn := connection(cons)
+		if conn == nil {
+			// unknown consumer tracks, but the only source is unavailable
+			if single {
+				s.stopConsumer(cons, ErrSourceUnavailable)
+			}
+			continue
+		}
+
+		for _, sender := range conn.Senders {
+			if msg.nodes[&sender.Node] {
+				s.stopConsumer(cons, ErrSourceUnavailable)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package streams
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// sessionConfig - session limits for consumer format
+type sessionConfig struct {
+	MaxDuration time.Duration `yaml:"max_duration"` // stop session after this time
+	IdleTimeout time.Duration `yaml:"idle_timeout"` // stop session if reader doesn't read data
+	MaxPerIP    int           `yaml:"max_per_ip"`   // max sessions of this format from one IP
+}
+
+// sessionConfigs - by format name ("mse/fmp4") or format prefix ("hls")
+type sessionConfigs map[string]sessionConfig
+
+var consumersSessions = sessionConfigs{}
+
+func (c sessionConfigs) get(format string) sessionConfig {
+	if conf, ok := c[format]; ok {
+		return conf
+	}
+	prefix, _, _ := strings.Cut(format, "/")
+	return c[prefix]
+}
+
+var (
+	ErrSessionDuration = errors.New("streams: session max duration reached")
+	ErrSessionIdle     = errors.New("streams: session idle timeout")
+)
+
+// CloseReason - why consumer was stopped by session limits, should be called before RemoveConsumer
+func (s *Stream) CloseReason(cons core.Consumer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reasons[cons]
+}
+
+// stopConsumer - stop consumer with reason, protocol handler will remove it
+func (s *Stream) stopConsumer(cons core.Consumer, reason error) {
+	s.mu.Lock()
+	ok := slices.Contains(s.consumers, cons)
+	if ok {
+		if s.reasons == nil {
+			s.reasons = map[core.Consumer]error{}
+		}
+		s.reasons[cons] = reason
+	}
+	s.mu.Unlock()
+
+	if ok {
+		log.Debug().Err(reason).Msgf("[streams] stop consumer format=%s", formatName(cons))
+		_ = cons.Stop()
+	}
+}
+
+// limitDuration - stop consumer after max session duration
+func (s *Stream) limitDuration(cons core.Consumer, d time.Duration) {
+	time.AfterFunc(d, func() {
+		s.stopConsumer(cons, ErrSessionDuration)
+	})
+}
+
+// limitIdle - stop consumer if it doesn't read data, so sender drops packets
+func (s *Stream) limitIdle(cons core.Consumer, d time.Duration) {
+	conn := connection(cons)
+	if conn == nil {
+		return
+	}
+
+	var packets, drops int
+
+	for {
+		time.Sleep(d)
+
+		s.mu.Lock()
+		ok := slices.Contains(s.consumers, cons)
+		s.mu.Unlock()
+
+		if !ok {
+			return
+		}
+
+		var newPackets, newDrops int
+		for _, sender := range conn.Senders {
+			newPackets += sender.Packets
+			newDrops += sender.Drops
+		}
+
+		// source has data, but reader doesn't take it
+		if newPackets == packets && newDrops > drops {
+			s.stopConsumer(cons, ErrSessionIdle)
+			return
+		}
+
+		packets, drops = newPackets, newDrops
+	}
+}
+
+// checkSessionsPerIP - count consumers of the same format from the same IP in all streams
+func checkSessionsPerIP(cons core.Consumer, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	conn := connection(cons)
+	if conn == nil || conn.RemoteAddr == "" {
+		return nil
+	}
+
+	format := conn.FormatName
+	host := remoteHost(conn.RemoteAddr)
+
+	var n int
+	for stream := range streamNames() {
+		stream.mu.Lock()
+		for _, consumer := range stream.consumers {
+			if c := connection(consumer); c != nil && c.FormatName == format && remoteHost(c.RemoteAddr) == host {
+				n++
+			}
+		}
+		stream.mu.Unlock()
+	}
+
+	if n >= limit {
+		return fmt.Errorf("%w (per IP <= %d)", ErrConsumersLimit, limit)
+	}
+
+	return nil
+}
+
+// remoteHost - client IP from "ip:port forwarded client, proxy", so all viewers
+// behind the reverse proxy don't count as one IP
+func remoteHost(remoteAddr string) string {
+	if _, forwarded, ok := strings.Cut(remoteAddr, " forwarded "); ok {
+		remoteAddr, _, _ = strings.Cut(forwarded, ",")
+		remoteAddr = strings.TrimSpace(remoteAddr)
+	}
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return strings.Trim(remoteAddr, "[]")
+}
@@ -0,0 +1,47 @@
+package streams
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrShutdown = errors.New("streams: shutting down")
+
+// shutdown - stop all consumers and producers, so sources get RTSP TEARDOWN or RTMP close
+func shutdown() {
+	streamsMu.Lock()
+	unique := make(map[*Stream]struct{}, len(streams))
+	for _, stream := range streams {
+		unique[stream] = struct{}{}
+	}
+	streamsMu.Unlock()
+
+	var wg sync.WaitGroup
+	for stream := range unique {
+		wg.Add(1)
+		go func(s *Stream) {
+			s.shutdown()
+			wg.Done()
+		}(stream)
+	}
+	wg.Wait()
+}
+
+func (s *Stream) shutdown() {
+	s.stopKeepalive()
+	s.stopPublishers()
+
+	s.mu.Lock()
+	consumers := s.consumers
+	producers := s.producers
+	s.mu.Unlock()
+
+	// publish targets and viewers
+	for _, cons := range consumers {
+		_ = cons.Stop()
+	}
+
+	for _, prod := range producers {
+		prod.stop()
+	}
+}
@@ -0,0 +1,105 @@
+package streams
+
+import "github.com/AlexxIT/go2rtc/pkg/core"
+
+// ProducerStats - producer counters for metrics
+type ProducerStats struct {
+	URL        string
+	FormatName string
+	State      string
+	Reconnects int
+	Bytes      int
+	Packets    int
+}
+
+// ConsumerStats - consumer counters for metrics
+type ConsumerStats struct {
+	FormatName string
+	Bytes      int
+	Packets    int
+	Drops      int
+}
+
+type StreamStats struct {
+	Producers []ProducerStats
+	Consumers []ConsumerStats
+	Closed    []ConsumerStats // totals of closed consumers by format
+}
+
+// Stats - snapshot of stream counters, producer values are reset on reconnect,
+// like any Prometheus counter on restart
+func (s *Stream) Stats() (stats StreamStats) {
+	s.mu.Lock()
+	producers := s.producers
+	consumers := s.consumers
+	for _, closed := range s.closed {
+		stats.Closed = append(stats.Closed, closed)
+	}
+	s.mu.Unlock()
+
+	for _, prod := range producers {
+		prod.mu.Lock()
+		info := ProducerStats{
+			URL:        prod.url,
+			FormatName: formatName(prod.conn),
+			State:      prod.state.String(),
+			Reconnects: prod.reconnects,
+		}
+		for _, receiver := range prod.receivers {
+			info.Bytes += receiver.Bytes
+			info.Packets += receiver.Packets
+		}
+		prod.mu.Unlock()
+
+		stats.Producers = append(stats.Producers, info)
+	}
+
+	for _, cons := range consumers {
+		stats.Consumers = append(stats.Consumers, consumerStats(cons))
+	}
+
+	return
+}
+
+func consumerStats(cons core.Consumer) ConsumerStats {
+	info := ConsumerStats{FormatName: formatName(cons)}
+	if conn := connection(cons); conn != nil {
+		for _, sender := range conn.Senders {
+			info.Bytes += sender.Bytes
+			info.Packets += sender.Packets
+			info.Drops += sender.Drops
+		}
+	}
+	return info
+}
+
+// closeStats - add counters of removed consumer to stream totals, so consumer
+// metrics don't go down on disconnect, should be called under s.mu
+func (s *Stream) closeStats(cons core.Consumer) {
+	info := consumerStats(cons)
+	if s.closed == nil {
+		s.closed = map[string]ConsumerStats{}
+	}
+	closed := s.closed[info.FormatName]
+	closed.FormatName = info.FormatName
+	closed.Bytes += info.Bytes
+	closed.Packets += info.Packets
+	closed.Drops += info.Drops
+	s.closed[info.FormatName] = closed
+}
+
+func (s state) String() string {
+	switch s {
+	case stateMedias:
+		return "medias"
+	case stateTracks:
+		return "tracks"
+	case stateStart:
+		return "start"
+	case stateExternal:
+		return "external"
+	case stateInternal:
+		return "internal"
+	}
+	return "none"
+}
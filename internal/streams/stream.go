@@ -4,23 +4,43 @@ import (
 	"encoding/json"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/AlexxIT/go2rtc/pkg/shell"
 )
 
 type Stream struct {
-	producers []*Producer
-	consumers []core.Consumer
-	mu        sync.Mutex
-	pending   atomic.Int32
+	name       string // for events
+	labels     map[string]string
+	producers  []*Producer
+	consumers  []core.Consumer
+	priorities map[core.Consumer]Priority
+	started    map[core.Consumer]time.Time
+	reasons    map[core.Consumer]error // why consumer was stopped by session limits
+	reserved   []core.Consumer         // consumers that passed limits check, but not added yet
+	publishers []*publisher
+	limit      int // consumers limit, 0 - use global, -1 - unlimited
+	mu         sync.Mutex
+	pending    atomic.Int32
+
+	failover    bool
+	failoverMu  sync.Mutex
+	failbackRun atomic.Bool
+
+	keepalive *keepalive
+
+	closed map[string]ConsumerStats // counters of removed consumers by format
 }
 
 func NewStream(source any) *Stream {
 	switch source := source.(type) {
 	case string:
-		return &Stream{
+		s := &Stream{
 			producers: []*Producer{NewProducer(source)},
 		}
+		s.listenProducer(s.producers[0])
+		return s
 	case []any:
 		s := new(Stream)
 		for _, src := range source {
@@ -29,11 +49,50 @@ func NewStream(source any) *Stream {
 				log.Error().Msgf("[stream] NewStream: Expected string, got %v", src)
 				continue
 			}
-			s.producers = append(s.producers, NewProducer(str))
+			prod := NewProducer(str)
+			s.listenProducer(prod)
+			s.producers = append(s.producers, prod)
 		}
 		return s
 	case map[string]any:
-		return NewStream(source["url"])
+		s := NewStream(source["url"])
+		s.labels = parseLabels(source)
+		if limit, ok := source["consumers_limit"].(int); ok {
+			s.limit = limit
+		}
+		if conf, ok := source["retry"].(map[string]any); ok {
+			retry := ParseRetry(conf, defaultRetry)
+			for _, prod := range s.producers {
+				prod.retry = &retry
+			}
+		}
+		if failover, ok := source["failover"].(bool); ok && failover {
+			s.enableFailover()
+		}
+		switch conf := source["watchdog"].(type) {
+		case map[string]any:
+			watchdog := ParseWatchdog(conf, defaultWatchdog)
+			for _, prod := range s.producers {
+				prod.watchdogConf = &watchdog
+			}
+		case bool:
+			if !conf {
+				for _, prod := range s.producers {
+					prod.watchdogConf = &Watchdog{}
+				}
+			}
+		}
+		if gop, ok := source["gop_cache"].(bool); ok && gop {
+			for _, prod := range s.producers {
+				prod.gop = &gopCache{}
+			}
+		}
+		if keepalive, ok := source["keepalive"].(bool); ok && keepalive {
+			s.enableKeepalive()
+		} else if alwaysOn, ok := source["always_on"].(bool); ok && alwaysOn {
+			s.enableKeepalive()
+		}
+		return s
 	case nil:
 		return new(Stream)
 	default:
@@ -57,15 +116,27 @@ func (s *Stream) SetSource(source string) {
 func (s *Stream) RemoveConsumer(cons core.Consumer) {
 	_ = cons.Stop()
 
+	var removed bool
+
 	s.mu.Lock()
+	started, reason := s.started[cons], s.reasons[cons]
 	for i, consumer := range s.consumers {
 		if consumer == cons {
 			s.consumers = append(s.consumers[:i], s.consumers[i+1:]...)
+			s.closeStats(cons)
+			removed = true
 			break
 		}
 	}
+	delete(s.priorities, cons)
+	delete(s.started, cons)
+	delete(s.reasons, cons)
 	s.mu.Unlock()
 
+	if removed {
+		s.fireConsumerEvent(EventConsumerDisconnect, cons, started, reason)
+	}
+
 	s.stopProducers()
 }
 
@@ -96,6 +167,9 @@ func (s *Stream) stopProducers() {
 	s.mu.Lock()
 producers:
 	for _, producer := range s.producers {
+		if producer.keepalive {
+			continue
+		}
 		for _, track := range producer.receivers {
 			if len(track.Senders()) > 0 {
 				continue producers
@@ -111,13 +185,49 @@ producers:
 	s.mu.Unlock()
 }
 
+// Online - stream has at least one running producer
+func (s *Stream) Online() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, producer := range s.producers {
+		producer.mu.Lock()
+		state := producer.state
+		producer.mu.Unlock()
+
+		switch state {
+		case stateStart, stateExternal, stateInternal:
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Stream) ConsumersCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.consumers)
+}
+
 func (s *Stream) MarshalJSON() ([]byte, error) {
 	var info = struct {
-		Producers []*Producer     `json:"producers"`
-		Consumers []core.Consumer `json:"consumers"`
+		Producers []*Producer       `json:"producers"`
+		Consumers []core.Consumer   `json:"consumers"`
+		Publish   []*publisher      `json:"publish,omitempty"`
+		Keepalive *Health           `json:"keepalive,omitempty"`
+		Labels    map[string]string `json:"labels,omitempty"`
 	}{
 		Producers: s.producers,
 		Consumers: s.consumers,
+		Publish:   s.publishers,
+		Keepalive: s.Health(),
+		Labels:    s.labels,
+	}
+	b, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
 	}
-	return json.Marshal(info)
+	// hide camera passwords from API output
+	return []byte(shell.MaskCredentials(string(b))), nil
 }
@@ -1,10 +1,13 @@
 package streams
 
 import (
+	"errors"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/pion/rtp"
 	"github.com/stretchr/testify/require"
 )
 
@@ -36,3 +39,568 @@ func TestTempate(t *testing.T) {
 	require.Equal(t, stream1, stream2)
 	require.Equal(t, "ffmpeg:rtsp://example.com#video=copy", stream1.producers[0].url)
 }
+
+type testConsumer struct {
+	core.Connection
+}
+
+func (c *testConsumer) AddTrack(*core.Media, *core.Codec, *core.Receiver) error {
+	return nil
+}
+
+func TestLimits(t *testing.T) {
+	viewer := &testConsumer{Connection: core.Connection{FormatName: "mse/fmp4"}}
+	recorder := &testConsumer{Connection: core.Connection{FormatName: "mp4"}}
+
+	stream := &Stream{limit: 1}
+	stream.consumers = []core.Consumer{viewer}
+	stream.priorities = map[core.Consumer]Priority{viewer: PriorityViewer}
+
+	// same priority can't evict
+	err := stream.checkLimits(&testConsumer{}, PriorityViewer)
+	require.ErrorIs(t, err, ErrConsumersLimit)
+	require.Len(t, stream.consumers, 1)
+
+	// higher priority evict viewer
+	require.Nil(t, stream.checkLimits(recorder, PriorityRecorder))
+	require.Len(t, stream.consumers, 0)
+
+	// format limit
+	consumersFormats = map[string]int{"mse/fmp4": 1}
+	defer func() { consumersFormats = map[string]int{} }()
+
+	stream = &Stream{limit: -1}
+	stream.consumers = []core.Consumer{viewer, recorder}
+	stream.priorities = map[core.Consumer]Priority{viewer: PriorityViewer, recorder: PriorityRecorder}
+
+	require.Nil(t, stream.checkLimits(&testConsumer{}, PriorityViewer))
+	require.ErrorIs(t, stream.checkLimits(&testConsumer{Connection: core.Connection{FormatName: "mse/fmp4"}}, PriorityViewer), ErrConsumersLimit)
+
+	// reserved slot until consumer is added
+	stream = &Stream{limit: 1}
+	require.Nil(t, stream.checkLimits(viewer, PriorityViewer))
+	require.ErrorIs(t, stream.checkLimits(recorder, PriorityRecorder), ErrConsumersLimit)
+
+	stream.releaseLocked(viewer)
+	require.Nil(t, stream.checkLimits(recorder, PriorityRecorder))
+}
+
+type testProducer struct {
+	core.Connection
+	done chan struct{}
+}
+
+func newTestProducer(string) (core.Producer, error) {
+	prod := &testProducer{done: make(chan struct{})}
+	prod.Medias = []*core.Media{
+		{
+			Kind:      core.KindVideo,
+			Direction: core.DirectionRecvonly,
+			Codecs:    []*core.Codec{{Name: core.CodecH264, ClockRate: 90000}},
+		},
+	}
+	return prod, nil
+}
+
+func (p *testProducer) Start() error {
+	<-p.done
+	return nil
+}
+
+func (p *testProducer) Stop() error {
+	close(p.done)
+	return p.Connection.Stop()
+}
+
+func TestFailover(t *testing.T) {
+	HandleFunc("main", newTestProducer)
+	HandleFunc("backup", newTestProducer)
+
+	stream := NewStream(map[string]any{"url": []any{"main:", "backup:"}, "failover": true})
+	primary, backup := stream.producers[0], stream.producers[1]
+
+	// simulate consumer on primary source
+	require.Nil(t, primary.Dial())
+	media := primary.GetMedias()[0]
+	track, err := primary.GetTrack(media, media.Codecs[0])
+	require.Nil(t, err)
+	sender := core.NewSender(media, track.Codec)
+	sender.WithParent(track)
+	primary.start()
+
+	stream.failoverFrom(primary)
+	require.True(t, backup.hasConsumers())
+	require.False(t, primary.hasConsumers())
+	require.Equal(t, stateNone, primary.state)
+
+	failbackInterval = time.Millisecond
+	stream.failback()
+	require.True(t, primary.hasConsumers())
+	require.Equal(t, stateNone, backup.state)
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := ParseRetry(map[string]any{
+		"initial_delay": "2s", "max_delay": 10, "max_attempts": 3,
+	}, RetryPolicy{Jitter: 0.5})
+
+	require.Equal(t, RetryPolicy{
+		InitialDelay: 2 * time.Second, MaxDelay: 10 * time.Second, Jitter: 0.5, MaxAttempts: 3,
+	}, policy)
+
+	policy.Jitter = 0
+	require.Equal(t, 2*time.Second, policy.Delay(0))
+	require.Equal(t, 4*time.Second, policy.Delay(1))
+	require.Equal(t, 10*time.Second, policy.Delay(5))
+	require.Equal(t, 10*time.Second, policy.Delay(1000))
+
+	require.False(t, policy.Exceeded(2))
+	require.True(t, policy.Exceeded(3))
+
+	policy.Jitter = 0.2
+	for i := 0; i < 100; i++ {
+		d := policy.Delay(0)
+		require.True(t, d >= 1600*time.Millisecond && d <= 2400*time.Millisecond)
+	}
+
+	// zero delay is clamped
+	policy = ParseRetry(map[string]any{"initial_delay": 0}, defaultRetry)
+	require.Equal(t, minRetryDelay, policy.InitialDelay)
+}
+
+func TestStopPublishers(t *testing.T) {
+	stream := &Stream{name: "camera1"}
+	pub := &publisher{url: "rtmp://10.0.0.1/live", retry: RetryPolicy{InitialDelay: time.Hour}, done: make(chan struct{})}
+	stream.publishers = []*publisher{pub}
+
+	done := make(chan struct{})
+	go func() {
+		stream.publishWorker(pub, nil, nil, errors.New("dial failed"))
+		close(done)
+	}()
+
+	stream.stopPublishers()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "publish worker not stopped")
+	}
+}
+
+func TestKeepalive(t *testing.T) {
+	HandleFunc("main", newTestProducer)
+
+	prod := NewProducer("main:")
+	prod.keepalive = true
+	stream := &Stream{producers: []*Producer{prod}, keepalive: &keepalive{done: make(chan struct{})}}
+
+	err := stream.keepaliveCheck()
+	require.Nil(t, err)
+	require.Equal(t, stateStart, prod.state)
+
+	stream.setHealth(err)
+	require.True(t, stream.Health().Online)
+
+	// producer without consumers is not stopped
+	stream.stopProducers()
+	require.Equal(t, stateStart, prod.state)
+
+	stream.stopKeepalive()
+	require.Equal(t, stateNone, prod.state)
+}
+
+func TestGOPCache(t *testing.T) {
+	media := &core.Media{Kind: core.KindVideo, Direction: core.DirectionRecvonly}
+	video := core.NewReceiver(media, &core.Codec{Name: core.CodecH264, PayloadType: core.PayloadTypeRAW})
+	audio := core.NewReceiver(media, &core.Codec{Name: core.CodecAAC, PayloadType: core.PayloadTypeRAW})
+
+	g := &gopCache{}
+	g.wrap(video)
+	g.wrap(audio)
+
+	pframe := []byte{0, 0, 0, 1, 0x41}
+	iframe := []byte{0, 0, 0, 1, 0x65}
+
+	video.Input(&core.Packet{Header: rtp.Header{Timestamp: 1}, Payload: pframe}) // skipped
+	video.Input(&core.Packet{Header: rtp.Header{Timestamp: 2}, Payload: iframe})
+	audio.Input(&core.Packet{Header: rtp.Header{Timestamp: 3}, Payload: []byte{1}})
+	video.Input(&core.Packet{Header: rtp.Header{Timestamp: 4}, Payload: pframe})
+
+	var timestamps []uint32
+	sender := core.NewSender(media, video.Codec)
+	sender.Handler = func(packet *core.Packet) {
+		timestamps = append(timestamps, packet.Timestamp)
+		if packet.Timestamp == 2 {
+			// live packet during replay doesn't wait for it and is queued after it
+			video.Input(&core.Packet{Header: rtp.Header{Timestamp: 6}, Payload: pframe})
+		}
+	}
+
+	err := g.addTrack(video, func() error {
+		sender.WithParent(video)
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, []uint32{2, 4}, timestamps)
+	require.Equal(t, 1, sender.Packets) // buffered until the sender start
+
+	// next keyframe removes previous GOP
+	video.Input(&core.Packet{Header: rtp.Header{Timestamp: 5}, Payload: iframe})
+	require.Len(t, g.entries, 1)
+}
+
+func TestWatchdog(t *testing.T) {
+	HandleFunc("main", newTestProducer)
+
+	watchdogInterval = time.Millisecond
+	prod := NewProducer("main:")
+	prod.watchdogConf = &Watchdog{Timeout: 10 * time.Millisecond}
+
+	frozen := make(chan struct{}, 10)
+	prod.Listen(func(msg any) {
+		if msg == EventProducerFrozen {
+			frozen <- struct{}{}
+		}
+	})
+
+	require.Nil(t, prod.Dial())
+	media := prod.GetMedias()[0]
+	track, err := prod.GetTrack(media, media.Codecs[0])
+	require.Nil(t, err)
+	sender := core.NewSender(media, track.Codec)
+	sender.WithParent(track)
+	prod.start()
+
+	prod.mu.Lock()
+	conn := prod.conn
+	prod.mu.Unlock()
+
+	select {
+	case <-frozen:
+	case <-time.After(time.Second):
+		require.FailNow(t, "watchdog timeout")
+	}
+
+	// consumer moved to the new connection
+	require.Eventually(t, func() bool {
+		prod.mu.Lock()
+		defer prod.mu.Unlock()
+		return prod.conn != conn
+	}, time.Second, time.Millisecond)
+	require.True(t, prod.hasConsumers())
+
+	prod.stop()
+}
+
+func TestPatterns(t *testing.T) {
+	patterns = nil
+	defer func() {
+		patterns = nil
+	}()
+
+	addPattern("panel_{id}", "rtsp://10.0.{id}.2/stream")
+	addPattern("cam_{host}_{ch}", map[string]any{
+		"url":          []any{"rtsp://{host}/ch{ch}", "ffmpeg:cam_{host}_{ch}#video=h264"},
+		"idle_timeout": "5m",
+	})
+
+	require.Equal(t, "rtsp://10.0.12.2/stream", patterns[0].match("panel_12"))
+	require.Nil(t, patterns[0].match("panel_"))
+	require.Nil(t, patterns[0].match("panel_1 -i x"))
+	require.Nil(t, patterns[0].match("xpanel_1"))
+
+	require.Equal(t, map[string]any{
+		"url":          []any{"rtsp://192.168.1.10/ch2", "ffmpeg:cam_192.168.1.10_2#video=h264"},
+		"idle_timeout": "5m",
+	}, patterns[1].match("cam_192.168.1.10_2"))
+	require.Equal(t, 5*time.Minute, patterns[1].idle)
+
+	stream := Get("panel_12")
+	require.NotNil(t, stream)
+	require.Equal(t, []string{"rtsp://10.0.12.2/stream"}, stream.Sources())
+	require.Equal(t, stream, Get("panel_12"))
+
+	Delete("panel_12")
+	delete(patternStreams, "panel_12")
+}
+
+func TestDiffTopology(t *testing.T) {
+	prev := map[uint32]*TopologyNode{
+		1: {ID: 1, Type: "producer", Bytes: 1000},
+		2: {ID: 2, Type: "consumer", Bytes: 500},
+	}
+	next := map[uint32]*TopologyNode{
+		1: {ID: 1, Type: "producer", Bytes: 3000},
+		3: {ID: 3, Type: "consumer", Bytes: 0},
+	}
+
+	added, removed, stats := diffTopology(prev, next, 2*time.Second)
+	require.Equal(t, []*TopologyNode{next[3]}, added)
+	require.Equal(t, []uint32{2}, removed)
+	require.Equal(t, []TopologyStats{{ID: 1, Bytes: 3000, Rate: 1000}}, stats)
+}
+
+func TestKickConsumer(t *testing.T) {
+	viewer := &testConsumer{Connection: core.Connection{ID: core.NewID(), FormatName: "mse/fmp4"}}
+
+	stream := &Stream{name: "camera1"}
+	stream.consumers = []core.Consumer{viewer}
+	stream.priorities = map[core.Consumer]Priority{viewer: PriorityViewer}
+	stream.started = map[core.Consumer]time.Time{viewer: time.Now()}
+
+	streamsMu.Lock()
+	streams["camera1"] = stream
+	streamsMu.Unlock()
+	defer Delete("camera1")
+
+	infos := Consumers()
+	require.Len(t, infos, 1)
+	require.Equal(t, viewer.ID, infos[0].ID)
+	require.Equal(t, "camera1", infos[0].Stream)
+	require.Equal(t, "viewer", infos[0].Priority)
+
+	require.False(t, KickConsumer(viewer.ID+1))
+	require.True(t, KickConsumer(viewer.ID))
+	require.Len(t, stream.consumers, 0)
+	require.Len(t, Consumers(), 0)
+}
+
+func TestSessionLimits(t *testing.T) {
+	consumersSessions = sessionConfigs{
+		"mse/fmp4": {MaxPerIP: 1},
+		"hls":      {IdleTimeout: time.Minute},
+	}
+	defer func() { consumersSessions = sessionConfigs{} }()
+
+	require.Equal(t, 1, consumersSessions.get("mse/fmp4").MaxPerIP)
+	require.Equal(t, time.Minute, consumersSessions.get("hls/mpegts").IdleTimeout)
+	require.Equal(t, sessionConfig{}, consumersSessions.get("rtsp"))
+
+	require.Equal(t, "192.168.1.2", remoteHost("192.168.1.2:51234"))
+	require.Equal(t, "10.0.0.1", remoteHost("192.168.1.2:51234 forwarded 10.0.0.1"))
+	require.Equal(t, "10.0.0.1", remoteHost("192.168.1.2:51234 forwarded 10.0.0.1, 172.16.0.1"))
+	require.Equal(t, "2001:db8::1", remoteHost("192.168.1.2:51234 forwarded [2001:db8::1]:1234"))
+
+	viewer := &testConsumer{Connection: core.Connection{FormatName: "mse/fmp4", RemoteAddr: "192.168.1.2:51234"}}
+
+	stream := &Stream{name: "camera1"}
+	stream.consumers = []core.Consumer{viewer}
+
+	streamsMu.Lock()
+	streams["camera1"] = stream
+	streamsMu.Unlock()
+	defer Delete("camera1")
+
+	second := &testConsumer{Connection: core.Connection{FormatName: "mse/fmp4", RemoteAddr: "192.168.1.2:51235"}}
+	require.ErrorIs(t, checkSessionsPerIP(second, 1), ErrConsumersLimit)
+
+	other := &testConsumer{Connection: core.Connection{FormatName: "mse/fmp4", RemoteAddr: "192.168.1.3:51235"}}
+	require.Nil(t, checkSessionsPerIP(other, 1))
+
+	stream.stopConsumer(viewer, ErrSessionDuration)
+	require.ErrorIs(t, stream.CloseReason(viewer), ErrSessionDuration)
+
+	stream.RemoveConsumer(viewer)
+	require.Nil(t, stream.CloseReason(viewer))
+}
+
+func TestLabels(t *testing.T) {
+	stream := NewStream(map[string]any{
+		"url":         "rtsp://10.0.1.2/stream",
+		"device_name": "Voskhod-26 (entrance 1)",
+		"labels":      map[string]any{"gate": "Voskhod-26", "entrance": 1},
+	})
+	stream.setName("panel_1")
+
+	require.Equal(t, "1", stream.Label("entrance"))
+	require.Equal(t, "Voskhod-26 (entrance 1)", stream.Label("device_name"))
+
+	require.True(t, stream.MatchLabels(parseSelectors([]string{"gate:Voskhod-26,entrance:1"})))
+	require.True(t, stream.MatchLabels([]string{"gate"}))
+	require.False(t, stream.MatchLabels([]string{"gate:Voskhod-27"}))
+	require.False(t, stream.MatchLabels([]string{"building"}))
+
+	require.Equal(t, "rtmp://example.com/live/Voskhod-26-panel_1-", stream.Expand("rtmp://example.com/live/{labels.gate}-{name}-{labels.unknown}"))
+}
+
+func TestHealthSummary(t *testing.T) {
+	prod := &Producer{url: "rtsp://192.168.1.123/stream", state: stateStart}
+	stream := &Stream{producers: []*Producer{prod}}
+
+	// running producer without packets
+	h := stream.HealthSummary("camera1")
+	require.Equal(t, "stale", h.Status)
+	require.Nil(t, h.Producers[0].LastPacket)
+
+	prod.lastPacket.Store(time.Now().UnixNano())
+	h = stream.HealthSummary("camera1")
+	require.Equal(t, "online", h.Status)
+	require.Equal(t, "start", h.Producers[0].State)
+	require.NotNil(t, h.Producers[0].LastPacket)
+
+	// consumers wait for reconnecting producer
+	prod.retryState = &RetryState{Attempt: 1, Error: "timeout"}
+	stream.consumers = []core.Consumer{&testConsumer{}}
+	h = stream.HealthSummary("camera1")
+	require.Equal(t, "offline", h.Status)
+	require.Equal(t, 1, h.Producers[0].Retry.Attempt)
+
+	// reconnect holds the producer lock while dialing
+	prod.mu.Lock()
+	h = stream.HealthSummary("camera1")
+	prod.mu.Unlock()
+	require.Equal(t, "offline", h.Status)
+
+	prod.state = stateNone
+	prod.retryState = nil
+	stream.consumers = nil
+	require.Equal(t, "idle", stream.HealthSummary("camera1").Status)
+}
+
+func TestProducerEventsOnce(t *testing.T) {
+	var events []*Message
+	OnEvent(func(msg *Message) {
+		if msg.Source == "rtsp://10.0.0.1/events" {
+			events = append(events, msg)
+		}
+	})
+
+	stream := NewStream("rtsp://10.0.0.1/events")
+	stream.setName("camera1")
+	stream.setName("camera2")
+
+	stream.producers[0].Fire(EventProducerOffline)
+	require.Len(t, events, 1)
+	require.Equal(t, "camera2", events[0].Stream)
+}
+
+func TestProducerStatus(t *testing.T) {
+	events := make(chan any, 10)
+	prod := NewProducer("unknown://10.0.0.1/status")
+	prod.Listen(func(msg any) {
+		events <- msg
+	})
+
+	require.Error(t, prod.Dial())
+	require.Error(t, prod.Dial())
+	require.Equal(t, EventProducerOffline, <-events)
+
+	prod.mu.Lock()
+	prod.setStatus(EventProducerOnline)
+	prod.setStatus(EventProducerOnline)
+	prod.mu.Unlock()
+	require.Equal(t, EventProducerOnline, <-events)
+
+	// same status isn't fired twice
+	time.Sleep(10 * time.Millisecond)
+	require.Len(t, events, 0)
+}
+
+func TestSourceUnavailable(t *testing.T) {
+	media := &core.Media{Kind: core.KindVideo, Direction: core.DirectionRecvonly}
+	codec := &core.Codec{Name: core.CodecH264}
+
+	receiver := core.NewReceiver(media, codec)
+	sender := core.NewSender(media, codec)
+	sender.WithParent(receiver)
+
+	viewer := &testConsumer{Connection: core.Connection{Senders: []*core.Sender{sender}}}
+	other := &testConsumer{Connection: core.Connection{Senders: []*core.Sender{core.NewSender(media, codec)}}}
+
+	prod := &Producer{receivers: []*core.Receiver{receiver}}
+	stream := &Stream{producers: []*Producer{prod, {}}}
+	stream.consumers = []core.Consumer{viewer, other}
+
+	stream.stopUnavailable(&sourceUnavailable{nodes: prod.nodes()})
+	require.Equal(t, ErrSourceUnavailable, stream.CloseReason(viewer))
+	require.Nil(t, stream.CloseReason(other))
+}
+
+func TestProducerMarshalRetry(t *testing.T) {
+	prod := &Producer{url: "rtsp://10.0.0.1/stream"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			prod.mu.Lock()
+			prod.setRetry(&RetryState{Attempt: i})
+			prod.mu.Unlock()
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		_, err := prod.MarshalJSON()
+		require.Nil(t, err)
+	}
+	<-done
+}
+
+func TestReplaceStream(t *testing.T) {
+	HandleFunc("main", newTestProducer)
+
+	streamsMu.Lock()
+	old := newStream("camera1", "main:")
+	streamsMu.Unlock()
+	old.keepalive = &keepalive{done: make(chan struct{})}
+
+	stream := New("camera1", "main:")
+	require.NotEqual(t, old, stream)
+	require.Equal(t, stream, Get("camera1"))
+
+	// old stream is shut down in background
+	require.Eventually(t, func() bool {
+		select {
+		case <-old.keepalive.done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+
+	Delete("camera1")
+}
+
+func TestValidateName(t *testing.T) {
+	require.Nil(t, ValidateName("camera1"))
+	require.Nil(t, ValidateName("camera.from_hass"))
+
+	require.NotNil(t, ValidateName(""))
+	require.NotNil(t, ValidateName("camera 1"))
+	require.NotNil(t, ValidateName("cameras/1"))
+	require.NotNil(t, ValidateName("panel_{id}"))
+	require.NotNil(t, ValidateName("panel_{"))
+}
+
+func TestKeepaliveWithoutSources(t *testing.T) {
+	stream := NewStream(map[string]any{"keepalive": true})
+	require.Nil(t, stream.Health())
+
+	// stream with external producers only
+	stream.keepalive = &keepalive{done: make(chan struct{})}
+	stream.setHealth(errors.New("no sources"))
+	require.False(t, stream.Health().Online)
+	stream.stopKeepalive()
+}
+
+func TestPublishRetry(t *testing.T) {
+	HandleConsumerFunc("test", func(string) (core.Consumer, func(), error) {
+		return &testConsumer{}, func() {}, nil
+	})
+
+	// stream without sources, so every attempt fails
+	stream := &Stream{name: "camera1"}
+	retry := RetryPolicy{InitialDelay: time.Hour}
+
+	require.Nil(t, stream.PublishWithRetry("test:1", retry))
+	require.Nil(t, stream.PublishWithRetry("test:1", retry))
+	require.Equal(t, []string{"test:1"}, stream.PublishURLs())
+
+	// target without next attempts isn't added
+	retry.MaxAttempts = 1
+	require.NotNil(t, stream.PublishWithRetry("test:2", retry))
+	require.Equal(t, []string{"test:1"}, stream.PublishURLs())
+
+	stream.stopPublishers()
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/AlexxIT/go2rtc/internal/api"
+	"github.com/AlexxIT/go2rtc/internal/api/ws"
 	"github.com/AlexxIT/go2rtc/internal/app"
 	"github.com/rs/zerolog"
 )
@@ -22,12 +23,38 @@ func Init() {
 
 	log = app.GetLogger("streams")
 
+	initLimits()
+	initRetry()
+	initWatchdog()
+
 	for name, item := range cfg.Streams {
-		streams[name] = NewStream(item)
+		// stream was deleted at runtime
+		if item == nil {
+			continue
+		}
+		if isPattern(name) {
+			addPattern(name, item)
+			continue
+		}
+		stream := NewStream(item)
+		stream.setName(name)
+		streams[name] = stream
+	}
+
+	if patterns != nil {
+		go patternsWorker()
 	}
 
 	api.HandleFunc("api/streams", apiStreams)
 	api.HandleFunc("api/streams.dot", apiStreamsDOT)
+	api.HandleFunc("api/consumers", apiConsumers)
+	api.HandleFunc("api/health/streams", apiHealthStreams)
+
+	initAPIv2()
+
+	ws.HandleFunc("topology", wsTopology)
+
+	app.OnShutdown("streams", shutdown)
 
 	if cfg.Publish == nil {
 		return
@@ -43,7 +70,9 @@ func Init() {
 }
 
 func Get(name string) *Stream {
-	return streams[name]
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+	return getLocked(name)
 }
 
 var sanitize = regexp.MustCompile(`\s`)
@@ -56,36 +85,59 @@ func Validate(source string) error {
 	return nil
 }
 
+var invalidName = regexp.MustCompile(`[\s/{}]`)
+
+// ValidateName - stream name from API can't have spaces, slashes and pattern placeholders
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("streams: name required")
+	}
+	if isPattern(name) || invalidName.MatchString(name) {
+		return errors.New("streams: invalid name")
+	}
+	return nil
+}
+
 func New(name string, source string) *Stream {
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+	return newStream(name, source)
+}
+
+func newStream(name string, source string) *Stream {
 	if Validate(source) != nil {
 		return nil
 	}
 
 	stream := NewStream(source)
-	streams[name] = stream
+	stream.setName(name)
+	replaceLocked(name, stream)
 	return stream
 }
 
 func Patch(name string, source string) *Stream {
 	streamsMu.Lock()
 	defer streamsMu.Unlock()
+	return patch(name, source)
+}
 
+func patch(name string, source string) *Stream {
 	// check if source links to some stream name from go2rtc
 	if u, err := url.Parse(source); err == nil && u.Scheme == "rtsp" && len(u.Path) > 1 {
 		rtspName := u.Path[1:]
-		if stream, ok := streams[rtspName]; ok {
+		if stream := getLocked(rtspName); stream != nil {
 			if streams[name] != stream {
 				// link (alias) streams[name] to streams[rtspName]
-				streams[name] = stream
+				replaceLocked(name, stream)
 			}
 			return stream
 		}
 	}
 
-	if stream, ok := streams[source]; ok {
-		if name != source {
+	if stream := getLocked(source); stream != nil {
+		if streams[name] != stream {
 			// link (alias) streams[name] to streams[source]
-			streams[name] = stream
+			replaceLocked(name, stream)
 		}
 		return stream
 	}
@@ -102,7 +154,7 @@ func Patch(name string, source string) *Stream {
 	}
 
 	// create new stream with this name
-	return New(name, source)
+	return newStream(name, source)
 }
 
 func GetOrPatch(query url.Values) *Stream {
@@ -113,7 +165,7 @@ func GetOrPatch(query url.Values) *Stream {
 	}
 
 	// check if src is stream name
-	if stream, ok := streams[source]; ok {
+	if stream := Get(source); stream != nil {
 		return stream
 	}
 
@@ -129,18 +181,67 @@ func GetOrPatch(query url.Values) *Stream {
 }
 
 func GetAll() (names []string) {
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
 	for name := range streams {
 		names = append(names, name)
 	}
 	return
 }
 
+// Streams - copy of all streams, safe for long operations without the lock
 func Streams() map[string]*Stream {
-	return streams
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+
+	copied := make(map[string]*Stream, len(streams))
+	for name, stream := range streams {
+		copied[name] = stream
+	}
+	return copied
 }
 
 func Delete(id string) {
+	streamsMu.Lock()
+	stream := deleteLocked(id)
+	streamsMu.Unlock()
+
+	if stream != nil {
+		stream.stopKeepalive()
+		stream.stopPublishers()
+	}
+}
+
+// deleteState - remove stream and its publish targets from the state file,
+// so they won't come back for a new stream with the same name
+func deleteState(name string) {
+	for _, path := range []string{"streams", "publish"} {
+		if err := app.PatchState(name, nil, path); err != nil {
+			log.Debug().Err(err).Msg("[streams] save state")
+			return
+		}
+	}
+}
+
+// deleteLocked - return deleted stream if it is not used by aliases
+func deleteLocked(id string) *Stream {
+	stream := streams[id]
 	delete(streams, id)
+	for _, s := range streams {
+		if s == stream {
+			return nil // stream still used by alias
+		}
+	}
+	return stream
+}
+
+// replaceLocked - set stream for the name and shut down the old stream with this name,
+// so its keepalive, publishers and producers don't stay running without a name
+func replaceLocked(name string, stream *Stream) {
+	if old := deleteLocked(name); old != nil && old != stream {
+		go old.shutdown()
+	}
+	streams[name] = stream
 }
 
 var log zerolog.Logger
@@ -0,0 +1,173 @@
+package streams
+
+import (
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/api/ws"
+	"github.com/AlexxIT/go2rtc/pkg/shell"
+)
+
+// TopologyNode - flat graph node for the topology feed
+type TopologyNode struct {
+	ID     uint32 `json:"id"`
+	Type   string `json:"type"` // producer, receiver, sender, consumer
+	Stream string `json:"stream"`
+	Parent uint32 `json:"parent,omitempty"` // connection for receivers and senders
+	Link   uint32 `json:"link,omitempty"`   // receiver for senders
+
+	Name       string `json:"name,omitempty"` // format name for connections, codec name for tracks
+	Protocol   string `json:"protocol,omitempty"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+	Source     string `json:"source,omitempty"`
+
+	Bytes int `json:"bytes"`
+	Rate  int `json:"rate"` // bytes per second
+}
+
+// TopologyStats - counters update for the node
+type TopologyStats struct {
+	ID    uint32 `json:"id"`
+	Bytes int    `json:"bytes"`
+	Rate  int    `json:"rate"`
+}
+
+// how often send topology updates
+var topologyInterval = time.Second
+
+// Topology - snapshot of all streams connections and tracks
+func Topology() map[uint32]*TopologyNode {
+	nodes := map[uint32]*TopologyNode{}
+	for stream, name := range streamNames() {
+		stream.appendTopology(nodes, name)
+	}
+	return nodes
+}
+
+func (s *Stream) appendTopology(nodes map[uint32]*TopologyNode, name string) {
+	s.mu.Lock()
+	producers := s.producers
+	consumers := s.consumers
+	s.mu.Unlock()
+
+	for _, prod := range producers {
+		prod.mu.Lock()
+		conn := prod.conn
+		prod.mu.Unlock()
+
+		if conn == nil {
+			continue
+		}
+
+		c, err := marshalConn(conn)
+		if err != nil {
+			continue
+		}
+
+		nodes[c.ID] = c.topologyNode("producer", name, c.BytesRecv)
+
+		for _, recv := range c.Receivers {
+			nodes[recv.ID] = &TopologyNode{
+				ID: recv.ID, Type: "receiver", Stream: name, Parent: c.ID, Name: recv.name(), Bytes: recv.Bytes,
+			}
+		}
+	}
+
+	for _, cons := range consumers {
+		c, err := marshalConn(cons)
+		if err != nil {
+			continue
+		}
+
+		nodes[c.ID] = c.topologyNode("consumer", name, c.BytesSend)
+
+		for _, send := range c.Senders {
+			nodes[send.ID] = &TopologyNode{
+				ID: send.ID, Type: "sender", Stream: name, Parent: c.ID, Link: send.Parent, Name: send.name(), Bytes: send.Bytes,
+			}
+		}
+	}
+}
+
+func (c *conn) topologyNode(typ, stream string, bytes int) *TopologyNode {
+	return &TopologyNode{
+		ID:         c.ID,
+		Type:       typ,
+		Stream:     stream,
+		Name:       c.FormatName,
+		Protocol:   c.Protocol,
+		RemoteAddr: c.RemoteAddr,
+		UserAgent:  c.UserAgent,
+		Source:     shell.MaskCredentials(c.Source),
+		Bytes:      bytes,
+	}
+}
+
+// diffTopology - calculate rates for the next snapshot and return changes
+func diffTopology(prev, next map[uint32]*TopologyNode, elapsed time.Duration) (added []*TopologyNode, removed []uint32, stats []TopologyStats) {
+	for id, node := range next {
+		old, ok := prev[id]
+		if !ok {
+			added = append(added, node)
+			continue
+		}
+
+		if seconds := elapsed.Seconds(); seconds > 0 && node.Bytes >= old.Bytes {
+			node.Rate = int(float64(node.Bytes-old.Bytes) / seconds)
+		}
+
+		if node.Bytes != old.Bytes || node.Rate != old.Rate {
+			stats = append(stats, TopologyStats{ID: id, Bytes: node.Bytes, Rate: node.Rate})
+		}
+	}
+
+	for id := range prev {
+		if _, ok := next[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+
+	return
+}
+
+// wsTopology - send full topology and then changes until WebSocket closed
+func wsTopology(tr *ws.Transport, _ *ws.Message) error {
+	done := make(chan struct{})
+	tr.OnClose(func() {
+		close(done)
+	})
+
+	prev := Topology()
+
+	nodes := make([]*TopologyNode, 0, len(prev))
+	for _, node := range prev {
+		nodes = append(nodes, node)
+	}
+	tr.Write(&ws.Message{Type: "topology", Value: nodes})
+
+	ts := time.Now()
+
+	for {
+		select {
+		case <-time.After(topologyInterval):
+		case <-done:
+			return nil
+		}
+
+		next := Topology()
+		now := time.Now()
+
+		added, removed, stats := diffTopology(prev, next, now.Sub(ts))
+		if added != nil {
+			tr.Write(&ws.Message{Type: "topology/add", Value: added})
+		}
+		if removed != nil {
+			tr.Write(&ws.Message{Type: "topology/remove", Value: removed})
+		}
+		if stats != nil {
+			tr.Write(&ws.Message{Type: "topology/stats", Value: stats})
+		}
+
+		prev, ts = next, now
+	}
+}
@@ -0,0 +1,163 @@
+package streams
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/pkg/core"
+)
+
+// Watchdog - force reconnect of producers with frozen video
+type Watchdog struct {
+	Timeout         time.Duration // max time without video packets, 0 - disabled
+	KeyframeTimeout time.Duration // max time without video keyframes, 0 - disabled
+}
+
+var defaultWatchdog Watchdog
+
+// how often check producers tracks
+var watchdogInterval = time.Second
+
+func initWatchdog() {
+	var cfg struct {
+		Watchdog map[string]any `yaml:"watchdog"`
+	}
+
+	app.LoadConfig(&cfg)
+
+	defaultWatchdog = ParseWatchdog(cfg.Watchdog, defaultWatchdog)
+}
+
+// ParseWatchdog - parse watchdog from config map with defaults from base
+func ParseWatchdog(conf map[string]any, base Watchdog) Watchdog {
+	if d, ok := parseDuration(conf["timeout"]); ok {
+		base.Timeout = d
+	}
+	if d, ok := parseDuration(conf["keyframe_timeout"]); ok {
+		base.KeyframeTimeout = d
+	}
+	return base
+}
+
+func (w *Watchdog) enabled() bool {
+	return w.Timeout > 0 || w.KeyframeTimeout > 0
+}
+
+// trackWatch - last video packet and keyframe arrival time in unix nanoseconds
+type trackWatch struct {
+	packet   atomic.Int64
+	keyframe atomic.Int64
+}
+
+// watchTrack - should be called under producer lock
+func (p *Producer) watchTrack(track *core.Receiver) {
+	watchdog := p.watchdogPolicy()
+	if !watchdog.enabled() || !track.Codec.IsVideo() {
+		return
+	}
+
+	w := &trackWatch{}
+	now := time.Now().UnixNano()
+	w.packet.Store(now)
+	w.keyframe.Store(now)
+
+	input := track.Input
+	track.Input = func(packet *core.Packet) {
+		now := time.Now().UnixNano()
+		w.packet.Store(now)
+		if isKeyframe(track.Codec, packet) {
+			w.keyframe.Store(now)
+		}
+		input(packet)
+	}
+
+	if p.watches == nil {
+		p.watches = map[*core.Receiver]*trackWatch{}
+	}
+	p.watches[track] = w
+}
+
+// watchdog - run while producer started, should be called under producer lock
+func (p *Producer) watchdog() {
+	if len(p.watches) == 0 {
+		return
+	}
+
+	p.watchdogID++
+	go p.watchdogWorker(p.watchdogID, watchdogInterval)
+}
+
+func (p *Producer) watchdogWorker(watchdogID int, interval time.Duration) {
+	watchdog := p.watchdogPolicy()
+
+	for {
+		time.Sleep(interval)
+
+		p.mu.Lock()
+
+		if p.state != stateStart || p.watchdogID != watchdogID {
+			p.mu.Unlock()
+			return
+		}
+
+		// producer already reconnecting
+		if p.retryState != nil {
+			p.mu.Unlock()
+			continue
+		}
+
+		reason := p.frozen(watchdog)
+		if reason == "" {
+			p.mu.Unlock()
+			continue
+		}
+
+		log.Warn().Msgf("[streams] watchdog: %s url=%s", reason, p.url)
+
+		// give the old tracks time if reconnect fails
+		now := time.Now().UnixNano()
+		for _, w := range p.watches {
+			w.packet.Store(now)
+			w.keyframe.Store(now)
+		}
+
+		// old worker won't reconnect when frozen connection stops
+		p.workerID++
+		workerID := p.workerID
+
+		p.mu.Unlock()
+
+		go p.Fire(EventProducerFrozen)
+
+		// consumers will be moved to the new connection
+		p.reconnect(workerID, 0)
+	}
+}
+
+// frozen - return reason if any video track is frozen
+func (p *Producer) frozen(watchdog *Watchdog) string {
+	now := time.Now().UnixNano()
+
+	for track, w := range p.watches {
+		if watchdog.Timeout > 0 && now-w.packet.Load() > int64(watchdog.Timeout) {
+			return "no " + track.Codec.Name + " packets for " + watchdog.Timeout.String()
+		}
+		if watchdog.KeyframeTimeout > 0 && hasKeyframes(track.Codec) && now-w.keyframe.Load() > int64(watchdog.KeyframeTimeout) {
+			return "no " + track.Codec.Name + " keyframes for " + watchdog.KeyframeTimeout.String()
+		}
+	}
+
+	return ""
+}
+
+func hasKeyframes(codec *core.Codec) bool {
+	return codec.Name == core.CodecH264 || codec.Name == core.CodecH265
+}
+
+func (p *Producer) watchdogPolicy() *Watchdog {
+	if p.watchdogConf != nil {
+		return p.watchdogConf
+	}
+	return &defaultWatchdog
+}
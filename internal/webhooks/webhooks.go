@@ -0,0 +1,155 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/rs/zerolog"
+)
+
+func Init() {
+	var cfg struct {
+		Mod []struct {
+			URL    string         `yaml:"url"`
+			Events []string       `yaml:"events"`
+			Secret string         `yaml:"secret"`
+			Retry  map[string]any `yaml:"retry"`
+		} `yaml:"webhooks"`
+	}
+
+	app.LoadConfig(&cfg)
+
+	if cfg.Mod == nil {
+		return
+	}
+
+	log = app.GetLogger("webhooks")
+
+	for _, item := range cfg.Mod {
+		if item.URL == "" {
+			log.Warn().Msg("[webhooks] empty url")
+			continue
+		}
+
+		hook := newHook(item.URL, item.Events, item.Secret, streams.ParseRetry(item.Retry, defaultRetry))
+		streams.OnEvent(hook.enqueue)
+		go hook.worker()
+	}
+}
+
+var log zerolog.Logger
+
+// max queued events per hook, new events are dropped when the receiver is too slow
+const queueSize = 100
+
+var defaultRetry = streams.RetryPolicy{
+	InitialDelay: time.Second,
+	MaxDelay:     time.Minute,
+	Jitter:       0.2,
+	MaxAttempts:  5,
+}
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+type hook struct {
+	url    string
+	events map[streams.Event]bool // nil - all events
+	secret []byte
+	retry  streams.RetryPolicy
+	queue  chan *streams.Message
+}
+
+func newHook(url string, events []string, secret string, retry streams.RetryPolicy) *hook {
+	h := &hook{
+		url:   url,
+		retry: retry,
+		queue: make(chan *streams.Message, queueSize),
+	}
+	if len(events) > 0 {
+		h.events = make(map[streams.Event]bool, len(events))
+		for _, event := range events {
+			h.events[streams.Event(event)] = true
+		}
+	}
+	if secret != "" {
+		h.secret = []byte(secret)
+	}
+	return h
+}
+
+func (h *hook) enqueue(msg *streams.Message) {
+	if h.events != nil && !h.events[msg.Event] {
+		return
+	}
+
+	select {
+	case h.queue <- msg:
+	default:
+		log.Warn().Msgf("[webhooks] queue is full, drop event=%s url=%s", msg.Event, h.url)
+	}
+}
+
+func (h *hook) worker() {
+	for msg := range h.queue {
+		body, err := json.Marshal(msg)
+		if err != nil {
+			continue
+		}
+
+		for attempt := 0; ; attempt++ {
+			if err = h.send(msg.Event, body); err == nil {
+				break
+			}
+
+			if h.retry.Exceeded(attempt + 1) {
+				log.Warn().Err(err).Msgf("[webhooks] drop event=%s url=%s", msg.Event, h.url)
+				break
+			}
+
+			log.Debug().Err(err).Msgf("[webhooks] retry event=%s url=%s", msg.Event, h.url)
+			time.Sleep(h.retry.Delay(attempt))
+		}
+	}
+}
+
+func (h *hook) send(event streams.Event, body []byte) error {
+	req, err := http.NewRequest("POST", h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Go2rtc-Event", string(event))
+
+	if h.secret != nil {
+		req.Header.Set("X-Go2rtc-Signature", "sha256="+Sign(h.secret, body))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.New("webhooks: wrong status: " + strconv.Itoa(res.StatusCode))
+	}
+
+	return nil
+}
+
+// Sign - HMAC-SHA256 of the request body in hex
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
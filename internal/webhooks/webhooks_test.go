@@ -0,0 +1,58 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlexxIT/go2rtc/internal/streams"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHook(t *testing.T) {
+	var requests atomic.Int32
+	received := make(chan *streams.Message, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fail first request for retry check
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Go2rtc-Signature") != "sha256="+Sign([]byte("secret"), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		msg := &streams.Message{}
+		_ = json.Unmarshal(body, msg)
+		received <- msg
+	}))
+	defer srv.Close()
+
+	retry := streams.RetryPolicy{InitialDelay: time.Millisecond, MaxAttempts: 3}
+	h := newHook(srv.URL, []string{"producer_online"}, "secret", retry)
+	go h.worker()
+
+	// filtered event
+	h.enqueue(&streams.Message{Event: streams.EventProducerOffline, Stream: "camera1"})
+	require.Len(t, h.queue, 0)
+
+	h.enqueue(&streams.Message{Event: streams.EventProducerOnline, Stream: "camera1"})
+
+	select {
+	case msg := <-received:
+		require.Equal(t, streams.EventProducerOnline, msg.Event)
+		require.Equal(t, "camera1", msg.Stream)
+	case <-time.After(time.Second):
+		require.FailNow(t, "timeout")
+	}
+
+	require.Equal(t, int32(2), requests.Load())
+}
@@ -111,7 +111,7 @@ func outputWebRTC(w http.ResponseWriter, r *http.Request) {
 	answer, err := ExchangeSDP(stream, offer, desc, r.UserAgent())
 	if err != nil {
 		log.Error().Err(err).Caller().Send()
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), streams.HTTPStatus(err))
 		return
 	}
 
@@ -3,6 +3,7 @@ package webrtc
 import (
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/AlexxIT/go2rtc/internal/api"
 	"github.com/AlexxIT/go2rtc/internal/api/ws"
@@ -21,6 +22,7 @@ func Init() {
 			Candidates []string         `yaml:"candidates"`
 			IceServers []pion.ICEServer `yaml:"ice_servers"`
 			Filters    webrtc.Filters   `yaml:"filters"`
+			Jitter     time.Duration    `yaml:"jitter_buffer"`
 		} `yaml:"webrtc"`
 	}
 
@@ -34,6 +36,7 @@ func Init() {
 	log = app.GetLogger("webrtc")
 
 	filters = cfg.Mod.Filters
+	webrtc.JitterLatency = cfg.Mod.Jitter
 
 	address, network, _ := strings.Cut(cfg.Mod.Listen, "/")
 	for _, candidate := range cfg.Mod.Candidates {
@@ -120,6 +123,7 @@ func asyncHandler(tr *ws.Transport, msg *ws.Message) error {
 	conn.Mode = mode
 	conn.Protocol = "ws"
 	conn.UserAgent = tr.Request.UserAgent()
+	conn.Session = tr.Request.Context().Value(core.SessionKey{})
 	conn.Listen(func(msg any) {
 		switch msg := msg.(type) {
 		case pion.PeerConnectionState:
@@ -128,6 +132,9 @@ func asyncHandler(tr *ws.Transport, msg *ws.Message) error {
 			}
 			switch mode {
 			case core.ModePassiveConsumer:
+				if err := stream.CloseReason(conn); err != nil {
+					tr.Write(&ws.Message{Type: "error", Value: "webrtc: " + err.Error()})
+				}
 				stream.RemoveConsumer(conn)
 			case core.ModePassiveProducer:
 				stream.RemoveProducer(conn)
@@ -217,6 +224,9 @@ func ExchangeSDP(stream *streams.Stream, offer, desc, userAgent string) (answer
 				return
 			}
 			if conn.Mode == core.ModePassiveConsumer {
+				if err := stream.CloseReason(conn); err != nil {
+					log.Debug().Err(err).Msg("[webrtc] close session")
+				}
 				stream.RemoveConsumer(conn)
 			} else {
 				stream.RemoveProducer(conn)
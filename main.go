@@ -4,6 +4,7 @@ import (
 	"github.com/AlexxIT/go2rtc/internal/api"
 	"github.com/AlexxIT/go2rtc/internal/api/ws"
 	"github.com/AlexxIT/go2rtc/internal/app"
+	"github.com/AlexxIT/go2rtc/internal/audit"
 	"github.com/AlexxIT/go2rtc/internal/bubble"
 	"github.com/AlexxIT/go2rtc/internal/cronjobs"
 	"github.com/AlexxIT/go2rtc/internal/debug"
@@ -19,9 +20,11 @@ import (
 	"github.com/AlexxIT/go2rtc/internal/http"
 	"github.com/AlexxIT/go2rtc/internal/isapi"
 	"github.com/AlexxIT/go2rtc/internal/ivideon"
+	"github.com/AlexxIT/go2rtc/internal/metrics"
 	"github.com/AlexxIT/go2rtc/internal/mjpeg"
 	"github.com/AlexxIT/go2rtc/internal/mp4"
 	"github.com/AlexxIT/go2rtc/internal/mpegts"
+	"github.com/AlexxIT/go2rtc/internal/mqtt"
 	"github.com/AlexxIT/go2rtc/internal/nest"
 	"github.com/AlexxIT/go2rtc/internal/ngrok"
 	"github.com/AlexxIT/go2rtc/internal/onvif"
@@ -32,9 +35,9 @@ import (
 	"github.com/AlexxIT/go2rtc/internal/srtp"
 	"github.com/AlexxIT/go2rtc/internal/streams"
 	"github.com/AlexxIT/go2rtc/internal/tapo"
+	"github.com/AlexxIT/go2rtc/internal/webhooks"
 	"github.com/AlexxIT/go2rtc/internal/webrtc"
 	"github.com/AlexxIT/go2rtc/internal/webtorrent"
-	"github.com/AlexxIT/go2rtc/pkg/shell"
 )
 
 func main() {
@@ -57,6 +60,9 @@ func main() {
 	// 2.999. Smart-Operator
 	record.Init()   // record module
 	cronjobs.Init() // cron jobs
+	mqtt.Init()     // MQTT control plane
+	webhooks.Init() // stream lifecycle webhooks
+	audit.Init()    // viewers audit log
 
 	// 3. Main API
 
@@ -91,11 +97,12 @@ func main() {
 
 	// 6. Helper modules
 
-	ngrok.Init() // ngrok module
-	srtp.Init()  // SRTP server
-	debug.Init() // debug API
+	ngrok.Init()   // ngrok module
+	srtp.Init()    // SRTP server
+	debug.Init()   // debug API
+	metrics.Init() // Prometheus metrics API
 
 	// 7. Go
 
-	shell.RunUntilSignal()
+	app.RunUntilSignal()
 }
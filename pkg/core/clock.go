@@ -0,0 +1,45 @@
+package core
+
+import (
+	"time"
+)
+
+// Clock - RTP timestamp to wall clock mapping, ex. from RTCP Sender Report
+type Clock struct {
+	Time      time.Time `json:"time"`
+	Timestamp uint32    `json:"timestamp"`
+	ClockRate uint32    `json:"clock_rate"`
+	Local     time.Time `json:"local"` // server time when mapping was received
+}
+
+// Offset - difference between source clock and server clock (without network delay)
+func (c *Clock) Offset() time.Duration {
+	return c.Time.Sub(c.Local)
+}
+
+// WallClock - capture time of the packet with RTP timestamp
+func (c *Clock) WallClock(timestamp uint32) time.Time {
+	if c.ClockRate == 0 {
+		return c.Time
+	}
+	// signed diff supports timestamps before the mapping and timestamps overflow
+	diff := int64(int32(timestamp - c.Timestamp))
+	return c.Time.Add(time.Duration(diff * int64(time.Second) / int64(c.ClockRate)))
+}
+
+// seconds from 1900 (NTP epoch) to 1970 (Unix epoch)
+const ntpEpochOffset = 2208988800
+
+// NTPToTime - convert 64-bit NTP timestamp (RFC 5905) to time
+func NTPToTime(ntp uint64) time.Time {
+	sec := int64(ntp>>32) - ntpEpochOffset
+	nsec := int64(ntp&0xFFFFFFFF) * int64(time.Second) >> 32
+	return time.Unix(sec, nsec)
+}
+
+// TimeToNTP - convert time to 64-bit NTP timestamp (RFC 5905)
+func TimeToNTP(t time.Time) uint64 {
+	sec := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return sec<<32 | frac
+}
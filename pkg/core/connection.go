@@ -49,6 +49,15 @@ type Connection struct {
 	Send      int         `json:"bytes_send,omitempty"`
 
 	Transport any `json:"-"`
+	Session   any `json:"-"` // auth session of the connection, see api.Session
+}
+
+// SessionKey - http.Request context key with the session of the authorized request
+type SessionKey struct{}
+
+// GetConnection - access to base info from any Producer or Consumer with Connection
+func (c *Connection) GetConnection() *Connection {
+	return c
 }
 
 func (c *Connection) GetMedias() []*Media {
@@ -121,6 +130,7 @@ func (c *Connection) WithRequest(r *http.Request) {
 	}
 
 	c.UserAgent = r.UserAgent()
+	c.Session = r.Context().Value(SessionKey{})
 }
 
 // Create like os.Create, init Consumer with existing Transport
@@ -3,6 +3,7 @@ package core
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -118,3 +119,21 @@ func TestName(t *testing.T) {
 	// stage3
 	_ = prod2.Stop()
 }
+
+func TestClock(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 500_000_000, time.UTC)
+	require.Equal(t, uint64(0xE93D_FBA5_8000_0000), TimeToNTP(ts))
+	require.True(t, ts.Equal(NTPToTime(TimeToNTP(ts))))
+
+	clock := &Clock{Time: ts, Timestamp: 0xFFFF_0000, ClockRate: 90000}
+	require.Equal(t, ts.Add(time.Second), clock.WallClock(24464)) // 0xFFFF_0000 + 90000 with overflow
+	require.Equal(t, ts.Add(-time.Second), clock.WallClock(0xFFFF_0000-90000))
+
+	receiver := NewReceiver(nil, &Codec{})
+	sender := NewSender(nil, &Codec{})
+	sender.WithParent(receiver)
+	require.Nil(t, sender.Clock())
+
+	receiver.SetClock(clock)
+	require.Equal(t, clock, sender.Clock())
+}
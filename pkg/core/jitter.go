@@ -0,0 +1,187 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	jitterSize        = 512 // max packets in the buffer
+	jitterMaxMisorder = 100 // older packets mean source restart
+)
+
+// JitterBuffer - reorder RTP packets by sequence number, remove duplicates and count lost packets.
+// Packets after the gap are released when they wait longer than Latency, on new packet arrival
+// or by timer, so they don't stall when the source pauses.
+type JitterBuffer struct {
+	Latency time.Duration `json:"latency"`
+
+	Lost       int `json:"lost,omitempty"`
+	Duplicates int `json:"duplicates,omitempty"` // also too late packets
+	Reordered  int `json:"reordered,omitempty"`
+
+	slots [jitterSize]jitterSlot
+	next  uint16 // next sequence number for output
+	last  uint16 // max received sequence number
+	count int    // packets in the buffer
+	init  bool
+
+	handler HandlerFunc
+	timer   *time.Timer
+
+	mu    sync.Mutex
+	outMu sync.Mutex // keeps output order between the receiver and the timer
+}
+
+type jitterSlot struct {
+	packet *Packet
+	ts     time.Time
+}
+
+func NewJitterBuffer(latency time.Duration) *JitterBuffer {
+	return &JitterBuffer{Latency: latency}
+}
+
+// Filter - can be used as core.Filter in front of the Receiver
+func (j *JitterBuffer) Filter(handler HandlerFunc) HandlerFunc {
+	j.handler = handler
+	return func(packet *Packet) {
+		j.mu.Lock()
+		j.output(j.push(packet, time.Now()))
+	}
+}
+
+func (j *JitterBuffer) onTimer() {
+	j.mu.Lock()
+	j.output(j.expire(nil, time.Now()))
+}
+
+// output - schedule next release and send packets, should be called under lock, unlocks it
+func (j *JitterBuffer) output(packets []*Packet) {
+	j.schedule()
+
+	j.outMu.Lock()
+	j.mu.Unlock()
+
+	for _, packet := range packets {
+		j.handler(packet)
+	}
+	j.outMu.Unlock()
+}
+
+// schedule - wake up when the oldest packet after the gap waits Latency
+func (j *JitterBuffer) schedule() {
+	if j.count == 0 {
+		if j.timer != nil {
+			j.timer.Stop()
+		}
+		return
+	}
+
+	d := j.Latency - time.Since(j.slots[j.oldest()%jitterSize].ts)
+	if j.timer == nil {
+		j.timer = time.AfterFunc(d, j.onTimer)
+	} else {
+		j.timer.Reset(d)
+	}
+}
+
+// push - add packet and return packets ready for output
+func (j *JitterBuffer) push(packet *Packet, now time.Time) (out []*Packet) {
+	seq := packet.SequenceNumber
+
+	if !j.init {
+		j.init = true
+		j.next = seq
+		j.last = seq
+	}
+
+	switch diff := int16(seq - j.next); {
+	case diff < -jitterMaxMisorder || diff >= jitterSize:
+		// source restarted or very long gap, so output all and start from this packet
+		out = j.flush()
+		j.next = seq
+		j.last = seq
+	case diff < 0:
+		// packet already released or skipped as lost
+		j.Duplicates++
+		return
+	}
+
+	slot := &j.slots[seq%jitterSize]
+	if slot.packet != nil {
+		j.Duplicates++
+		return
+	}
+
+	if int16(seq-j.last) < 0 {
+		j.Reordered++
+	} else {
+		j.last = seq
+	}
+
+	slot.packet = packet
+	slot.ts = now
+	j.count++
+
+	return j.expire(out, now)
+}
+
+// expire - release sequential packets and skip gaps, if the oldest packet after them waits too long
+func (j *JitterBuffer) expire(out []*Packet, now time.Time) []*Packet {
+	for j.count > 0 {
+		out = j.release(out)
+
+		if j.count == 0 {
+			break
+		}
+
+		oldest := j.oldest()
+		if now.Sub(j.slots[oldest%jitterSize].ts) < j.Latency {
+			break
+		}
+
+		j.Lost += int(oldest - j.next)
+		j.next = oldest
+	}
+
+	return out
+}
+
+// release - output all sequential packets from next
+func (j *JitterBuffer) release(out []*Packet) []*Packet {
+	for {
+		slot := &j.slots[j.next%jitterSize]
+		if slot.packet == nil {
+			return out
+		}
+		out = append(out, slot.packet)
+		slot.packet = nil
+		j.count--
+		j.next++
+	}
+}
+
+// oldest - first buffered sequence number after the gap
+func (j *JitterBuffer) oldest() uint16 {
+	seq := j.next
+	for j.slots[seq%jitterSize].packet == nil {
+		seq++
+	}
+	return seq
+}
+
+// flush - output all buffered packets in sequence order, gaps between them are lost
+func (j *JitterBuffer) flush() (out []*Packet) {
+	for seq := j.next; j.count > 0; seq++ {
+		slot := &j.slots[seq%jitterSize]
+		if slot.packet != nil {
+			out = append(out, slot.packet)
+			slot.packet = nil
+			j.count--
+		} else {
+			j.Lost++
+		}
+	}
+	return
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJitterBuffer(t *testing.T) {
+	j := NewJitterBuffer(100 * time.Millisecond)
+	now := time.Now()
+
+	var out []uint16
+	push := func(seq uint16, ts time.Time) {
+		for _, packet := range j.push(&Packet{Header: rtp.Header{SequenceNumber: seq}}, ts) {
+			out = append(out, packet.SequenceNumber)
+		}
+	}
+
+	// reorder with sequence overflow
+	push(65534, now)
+	push(0, now)
+	push(65535, now)
+	push(1, now)
+	require.Equal(t, []uint16{65534, 65535, 0, 1}, out)
+	require.Equal(t, 1, j.Reordered)
+
+	// duplicates
+	push(1, now)
+	push(3, now)
+	push(3, now)
+	require.Equal(t, 2, j.Duplicates)
+	require.Equal(t, []uint16{65534, 65535, 0, 1}, out)
+
+	// lost packet after latency
+	push(4, now.Add(50*time.Millisecond))
+	require.Len(t, out, 4)
+	push(5, now.Add(150*time.Millisecond))
+	require.Equal(t, []uint16{65534, 65535, 0, 1, 3, 4, 5}, out)
+	require.Equal(t, 1, j.Lost)
+
+	// late packet
+	push(2, now.Add(150*time.Millisecond))
+	require.Equal(t, 3, j.Duplicates)
+
+	// source restart
+	push(30000, now)
+	push(30001, now)
+	require.Equal(t, []uint16{65534, 65535, 0, 1, 3, 4, 5, 30000, 30001}, out)
+}
+
+func TestJitterFilter(t *testing.T) {
+	var out []uint16
+	handler := NewJitterBuffer(time.Second).Filter(func(packet *Packet) {
+		out = append(out, packet.SequenceNumber)
+	})
+
+	for _, seq := range []uint16{10, 12, 11, 13} {
+		handler(&Packet{Header: rtp.Header{SequenceNumber: seq}})
+	}
+	require.Equal(t, []uint16{10, 11, 12, 13}, out)
+}
+
+func TestJitterTimer(t *testing.T) {
+	var out []uint16
+	var mu sync.Mutex
+
+	j := NewJitterBuffer(50 * time.Millisecond)
+	handler := j.Filter(func(packet *Packet) {
+		mu.Lock()
+		out = append(out, packet.SequenceNumber)
+		mu.Unlock()
+	})
+
+	// source pauses after the gap
+	handler(&Packet{Header: rtp.Header{SequenceNumber: 20}})
+	handler(&Packet{Header: rtp.Header{SequenceNumber: 22}})
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(out) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	j.mu.Lock()
+	require.Equal(t, 1, j.Lost)
+	j.mu.Unlock()
+}
+
+func TestJitterFlushLost(t *testing.T) {
+	j := NewJitterBuffer(time.Second)
+	now := time.Now()
+
+	j.push(&Packet{Header: rtp.Header{SequenceNumber: 1}}, now)
+	j.push(&Packet{Header: rtp.Header{SequenceNumber: 3}}, now)
+	j.push(&Packet{Header: rtp.Header{SequenceNumber: 5}}, now)
+
+	// very long gap outputs buffered packets and counts gaps between them
+	out := j.push(&Packet{Header: rtp.Header{SequenceNumber: 2000}}, now)
+	require.Len(t, out, 3) // 3, 5 and 2000
+	require.Equal(t, 2, j.Lost)
+}
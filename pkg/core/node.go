@@ -2,6 +2,7 @@ package core
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"github.com/pion/rtp"
 )
@@ -30,6 +31,7 @@ type Node struct {
 	id     uint32
 	childs []*Node
 	parent *Node
+	clock  atomic.Pointer[Clock]
 
 	mu sync.Mutex
 }
@@ -47,6 +49,27 @@ func (n *Node) AppendChild(child *Node) {
 	child.parent = n
 }
 
+// Clock - wall clock mapping from the nearest parent, usually from the Receiver
+func (n *Node) Clock() *Clock {
+	for ; n != nil; n = n.parent {
+		if clock := n.clock.Load(); clock != nil {
+			return clock
+		}
+	}
+	return nil
+}
+
+func (n *Node) SetClock(clock *Clock) {
+	n.clock.Store(clock)
+}
+
+// Childs - copy of the childs list
+func (n *Node) Childs() []*Node {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*Node(nil), n.childs...)
+}
+
 func (n *Node) RemoveChild(child *Node) {
 	n.mu.Lock()
 	for i, ch := range n.childs {
@@ -180,11 +180,13 @@ func (r *Receiver) MarshalJSON() ([]byte, error) {
 		Childs  []uint32 `json:"childs,omitempty"`
 		Bytes   int      `json:"bytes,omitempty"`
 		Packets int      `json:"packets,omitempty"`
+		Clock   *Clock   `json:"clock,omitempty"`
 	}{
 		ID:      r.Node.id,
 		Codec:   r.Node.Codec,
 		Bytes:   r.Bytes,
 		Packets: r.Packets,
+		Clock:   r.Node.clock.Load(),
 	}
 	for _, child := range r.childs {
 		v.Childs = append(v.Childs, child.id)
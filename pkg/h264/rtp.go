@@ -101,6 +101,29 @@ func RTPDepay(codec *core.Codec, handler core.HandlerFunc) core.HandlerFunc {
 	}
 }
 
+// IsKeyframeRTP - check if RTP payload has IFrame (single NALU, STAP-A or first FU-A)
+func IsKeyframeRTP(payload []byte) bool {
+	if len(payload) < 2 {
+		return false
+	}
+
+	switch payload[0] & 0x1F {
+	case NALUTypeIFrame:
+		return true
+	case 24: // STAP-A
+		for i := 1; i+2 < len(payload); {
+			if payload[i+2]&0x1F == NALUTypeIFrame {
+				return true
+			}
+			i += 2 + int(binary.BigEndian.Uint16(payload[i:]))
+		}
+	case 28: // FU-A
+		return payload[1]&0x80 != 0 && payload[1]&0x1F == NALUTypeIFrame
+	}
+
+	return false
+}
+
 func RTPPay(mtu uint16, handler core.HandlerFunc) core.HandlerFunc {
 	if mtu == 0 {
 		mtu = 1472
@@ -88,6 +88,33 @@ func RTPDepay(codec *core.Codec, handler core.HandlerFunc) core.HandlerFunc {
 	}
 }
 
+// IsKeyframeRTP - check if RTP payload has IFrame (single NALU, AP or first FU)
+func IsKeyframeRTP(payload []byte) bool {
+	if len(payload) < 3 {
+		return false
+	}
+
+	switch (payload[0] >> 1) & 0x3F {
+	case NALUTypeIFrame, NALUTypeIFrame2, NALUTypeIFrame3:
+		return true
+	case 48: // AP
+		for i := 2; i+2 < len(payload); {
+			switch (payload[i+2] >> 1) & 0x3F {
+			case NALUTypeIFrame, NALUTypeIFrame2, NALUTypeIFrame3:
+				return true
+			}
+			i += 2 + int(binary.BigEndian.Uint16(payload[i:]))
+		}
+	case NALUTypeFU:
+		switch payload[2] & 0x3F {
+		case NALUTypeIFrame, NALUTypeIFrame2, NALUTypeIFrame3:
+			return payload[2]&0x80 != 0
+		}
+	}
+
+	return false
+}
+
 func RTPPay(mtu uint16, handler core.HandlerFunc) core.HandlerFunc {
 	if mtu == 0 {
 		mtu = 1472
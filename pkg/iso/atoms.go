@@ -30,6 +30,7 @@ const (
 	MoofTrafTfdt                = "tfdt"
 	MoofTrafTrun                = "trun"
 	Mdat                        = "mdat"
+	Prft                        = "prft"
 )
 
 const (
@@ -336,6 +337,17 @@ func (m *Movie) WriteMovieFragment(seq, tid, duration, size, flags uint32, dts u
 	m.EndAtom() // MOOF
 }
 
+// WriteProducerReferenceTime - NTP capture time of the sample with media time (decode time)
+func (m *Movie) WriteProducerReferenceTime(tid uint32, ntp, mediaTime uint64) {
+	m.StartAtom(Prft)
+	m.WriteBytes(1)          // version (64 bit media time)
+	m.WriteUint24(24)        // flags (captured time)
+	m.WriteUint32(tid)       // reference track id
+	m.WriteUint64(ntp)       // NTP timestamp
+	m.WriteUint64(mediaTime) // media time
+	m.EndAtom()
+}
+
 func (m *Movie) WriteData(b []byte) {
 	m.StartAtom(Mdat)
 	m.Write(b)
@@ -0,0 +1,84 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// header for HS256 tokens, the only supported algorithm
+const header = `{"alg":"HS256","typ":"JWT"}`
+
+var (
+	ErrFormat    = errors.New("jwt: wrong token format")
+	ErrAlgorithm = errors.New("jwt: unsupported algorithm")
+	ErrSignature = errors.New("jwt: wrong signature")
+)
+
+// Encode - make HS256 JSON Web Token with claims
+func Encode(claims any, secret []byte) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	s := encode([]byte(header)) + "." + encode(payload)
+	return s + "." + encode(sign(s, secret)), nil
+}
+
+// Decode - check HS256 signature and unmarshal claims, claims values should be checked by the caller
+func Decode(token string, secret []byte, claims any) error {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return ErrFormat
+	}
+
+	s, sig := token[:i], token[i+1:]
+
+	b64header, b64payload, ok := strings.Cut(s, ".")
+	if !ok {
+		return ErrFormat
+	}
+
+	b, err := decode(b64header)
+	if err != nil {
+		return ErrFormat
+	}
+
+	var h struct {
+		Alg string `json:"alg"`
+	}
+	if err = json.Unmarshal(b, &h); err != nil {
+		return ErrFormat
+	}
+	if h.Alg != "HS256" {
+		return ErrAlgorithm
+	}
+
+	if b, err = decode(sig); err != nil || !hmac.Equal(b, sign(s, secret)) {
+		return ErrSignature
+	}
+
+	if b, err = decode(b64payload); err != nil {
+		return ErrFormat
+	}
+
+	return json.Unmarshal(b, claims)
+}
+
+func sign(s string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(s))
+	return mac.Sum(nil)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
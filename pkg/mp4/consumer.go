@@ -163,6 +163,7 @@ func (c *Consumer) AddTrack(media *core.Media, _ *core.Codec, track *core.Receiv
 	}
 
 	c.muxer.AddTrack(codec)
+	c.muxer.SetClock(trackID, &handler.Node)
 
 	handler.HandleRTP(track)
 	c.Senders = append(c.Senders, handler)
@@ -16,6 +16,7 @@ type Muxer struct {
 	pts       []uint32
 	wroteInit bool
 	codecs    []*core.Codec
+	clocks    []*core.Node // nodes with wall clock mapping for prft
 
 	duration uint32
 
@@ -26,6 +27,12 @@ func (m *Muxer) AddTrack(codec *core.Codec) {
 	m.dts = append(m.dts, 0)
 	m.pts = append(m.pts, 0)
 	m.codecs = append(m.codecs, codec)
+	m.clocks = append(m.clocks, nil)
+}
+
+// SetClock - write prft box before keyframes if the node has wall clock mapping
+func (m *Muxer) SetClock(trackID byte, node *core.Node) {
+	m.clocks[trackID] = node
 }
 
 func (m *Muxer) GetInit() ([]byte, error) {
@@ -184,10 +191,14 @@ func (m *Muxer) GetPayload(trackID byte, packet *rtp.Packet) []byte {
 
 	//log.Printf("[MP4] idx:%3d trk:%d dts:%6d cts:%4d dur:%5d time:%10d len:%5d", m.index, trackID+1, m.dts[trackID], packet.SSRC, duration, packet.Timestamp, len(packet.Payload))
 
-	m.dts[trackID] += uint64(duration)
-
 	payload := mv.Bytes()
 
+	if flags == iso.SampleVideoIFrame {
+		payload = m.appendPrft(trackID, packet.Timestamp, payload)
+	}
+
+	m.dts[trackID] += uint64(duration)
+
 	// Reset was called, prepend init
 	if !m.wroteInit {
 		init, err := m.GetInit()
@@ -201,3 +212,20 @@ func (m *Muxer) GetPayload(trackID byte, packet *rtp.Packet) []byte {
 
 	return payload
 }
+
+// appendPrft - prft box should be before moof and can't be in the same Movie,
+// because moof data offset is calculated from the Movie start
+func (m *Muxer) appendPrft(trackID byte, timestamp uint32, payload []byte) []byte {
+	if m.clocks == nil || m.clocks[trackID] == nil {
+		return payload
+	}
+
+	clock := m.clocks[trackID].Clock()
+	if clock == nil {
+		return payload
+	}
+
+	mv := iso.NewMovie(32+len(payload), 0)
+	mv.WriteProducerReferenceTime(uint32(trackID+1), core.TimeToNTP(clock.WallClock(timestamp)), m.dts[trackID])
+	return append(mv.Bytes(), payload...)
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io"
 	"net"
+	"sync"
 	"time"
 )
 
@@ -14,6 +15,7 @@ const Timeout = time.Second * 5
 type Client struct {
 	conn net.Conn
 	mid  uint16
+	mu   sync.Mutex
 }
 
 func NewClient(conn net.Conn) *Client {
@@ -21,11 +23,19 @@ func NewClient(conn net.Conn) *Client {
 }
 
 func (c *Client) Connect(clientID, username, password string) (err error) {
+	return c.connect(NewConnect(clientID, username, password))
+}
+
+// ConnectWill - connect with retained last will, that broker will publish on connection lost
+func (c *Client) ConnectWill(clientID, username, password, willTopic string, willPayload []byte) error {
+	return c.connect(NewConnectWill(clientID, username, password, willTopic, willPayload))
+}
+
+func (c *Client) connect(msg *Message) (err error) {
 	if err = c.conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
 		return
 	}
 
-	msg := NewConnect(clientID, username, password)
 	if _, err = c.conn.Write(msg.b); err != nil {
 		return
 	}
@@ -43,6 +53,9 @@ func (c *Client) Connect(clientID, username, password string) (err error) {
 }
 
 func (c *Client) Subscribe(topic string) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if err = c.conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
 		return
 	}
@@ -54,6 +67,9 @@ func (c *Client) Subscribe(topic string) (err error) {
 }
 
 func (c *Client) Publish(topic string, payload []byte) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if err = c.conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
 		return
 	}
@@ -64,6 +80,27 @@ func (c *Client) Publish(topic string, payload []byte) (err error) {
 	return
 }
 
+// PublishRetain - QoS 0 message, that broker will keep for new subscribers
+func (c *Client) PublishRetain(topic string, payload []byte) error {
+	return c.write(NewPublishRetain(topic, payload).b)
+}
+
+func (c *Client) Ping() error {
+	return c.write([]byte{PINGREQ, 0})
+}
+
+func (c *Client) write(b []byte) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err = c.conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
+		return
+	}
+
+	_, err = c.conn.Write(b)
+	return
+}
+
 func (c *Client) Read() (string, []byte, error) {
 	if err := c.conn.SetDeadline(time.Now().Add(Timeout)); err != nil {
 		return "", nil, err
@@ -101,12 +138,12 @@ func (c *Client) Read() (string, []byte, error) {
 	}
 
 	// response with packet ID
-	_, _ = c.conn.Write([]byte{PUBACK, 2, b[i], b[i+1]})
+	_ = c.write([]byte{PUBACK, 2, b[i], b[i+1]})
 
-	return string(b[2:i]), b[i+2:], nil
+	return string(b[:i]), b[i+2:], nil
 }
 
 func (c *Client) Close() error {
-	// TODO: Teardown
+	_ = c.write([]byte{DISCONNECT, 0})
 	return c.conn.Close()
 }
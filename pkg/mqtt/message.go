@@ -10,13 +10,17 @@ type Message struct {
 
 // https://docs.oasis-open.org/mqtt/mqtt/v5.0/mqtt-v5.0.html
 const (
-	CONNECT   = 0x10
-	CONNACK   = 0x20
-	PUBLISH   = 0x30
-	PUBACK    = 0x40
-	SUBSCRIBE = 0x82
-	SUBACK    = 0x90
-	QOS1      = 0x02
+	CONNECT    = 0x10
+	CONNACK    = 0x20
+	PUBLISH    = 0x30
+	PUBACK     = 0x40
+	SUBSCRIBE  = 0x82
+	SUBACK     = 0x90
+	PINGREQ    = 0xC0
+	PINGRESP   = 0xD0
+	DISCONNECT = 0xE0
+	QOS1       = 0x02
+	RETAIN     = 0x01
 )
 
 func (m *Message) WriteByte(b byte) {
@@ -52,6 +56,8 @@ func (m *Message) Bytes() []byte {
 
 const (
 	flagCleanStart = 0x02
+	flagWill       = 0x04
+	flagWillRetain = 0x20
 	flagUsername   = 0x80
 	flagPassword   = 0x40
 )
@@ -72,6 +78,42 @@ func NewConnect(clientID, username, password string) *Message {
 	return m
 }
 
+// NewConnectWill - connect with retained last will message,
+// username and password are optional
+func NewConnectWill(clientID, username, password, willTopic string, willPayload []byte) *Message {
+	flags := byte(flagCleanStart | flagWill | flagWillRetain)
+	size := 12 + len(clientID) + 4 + len(willTopic) + len(willPayload)
+	if username != "" {
+		flags |= flagUsername
+		size += 2 + len(username)
+	}
+	if password != "" {
+		flags |= flagPassword
+		size += 2 + len(password)
+	}
+
+	m := &Message{}
+	m.WriteByte(CONNECT)
+	m.WriteLen(size)
+
+	m.WriteString("MQTT")
+	m.WriteByte(4) // MQTT version
+	m.WriteByte(flags)
+	m.WriteUint16(30) // keepalive
+
+	m.WriteString(clientID)
+	m.WriteString(willTopic)
+	m.WriteUint16(uint16(len(willPayload)))
+	m.WriteBytes(willPayload)
+	if username != "" {
+		m.WriteString(username)
+	}
+	if password != "" {
+		m.WriteString(password)
+	}
+	return m
+}
+
 func NewSubscribe(mid uint16, topic string, qos byte) *Message {
 	m := &Message{}
 	m.WriteByte(SUBSCRIBE)
@@ -93,6 +135,16 @@ func NewPublish(topic string, payload []byte) *Message {
 	return m
 }
 
+func NewPublishRetain(topic string, payload []byte) *Message {
+	m := &Message{}
+	m.WriteByte(PUBLISH | RETAIN)
+	m.WriteLen(2 + len(topic) + len(payload))
+
+	m.WriteString(topic)
+	m.WriteBytes(payload)
+	return m
+}
+
 func NewPublishQOS1(mid uint16, topic string, payload []byte) *Message {
 	m := &Message{}
 	m.WriteByte(PUBLISH | QOS1)
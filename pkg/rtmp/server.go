@@ -140,6 +140,10 @@ func (c *Conn) acceptCommand(b []byte) error {
 		c.Intent = cmd
 		c.streamID = 1
 
+		if len(items) >= 4 {
+			c.Stream, _ = items[3].(string)
+		}
+
 	case CommandFCPublish: // no response
 
 	default:
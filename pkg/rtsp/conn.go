@@ -27,6 +27,7 @@ type Conn struct {
 	Media       string
 	OnClose     func() error
 	PacketSize  uint16
+	Reject      string // custom DESCRIBE error status, default "404 Not Found"
 	SessionName string
 	Timeout     int
 	Transport   string // custom transport support, ex. RTSP over WebSocket
@@ -37,6 +38,7 @@ type Conn struct {
 
 	auth      *tcp.Auth
 	conn      net.Conn
+	denied    bool
 	keepalive int
 	mode      core.Mode
 	playOK    bool
@@ -47,8 +49,13 @@ type Conn struct {
 
 	state   State
 	stateMu sync.Mutex
+
+	writers map[byte]core.HandlerFunc // RTP input by channel, with jitter buffer
 }
 
+// JitterLatency - reorder incoming RTP packets with this latency, 0 - disabled
+var JitterLatency time.Duration
+
 const (
 	ProtoRTSP      = "RTSP/1.0"
 	MethodOptions  = "OPTIONS"
@@ -237,11 +244,8 @@ func (c *Conn) Handle() (err error) {
 				return
 			}
 
-			for _, receiver := range c.Receivers {
-				if receiver.ID == channelID {
-					receiver.WriteRTP(packet)
-					break
-				}
+			if write := c.writer(channelID); write != nil {
+				write(packet)
 			}
 		} else {
 			msg := &RTCP{Channel: channelID}
@@ -255,6 +259,8 @@ func (c *Conn) Handle() (err error) {
 				continue
 			}
 
+			c.handleRTCP(msg)
+
 			c.Fire(msg)
 		}
 
@@ -360,3 +366,49 @@ func (c *Conn) ReadResponse() (*tcp.Response, error) {
 	}
 	return tcp.ReadResponse(c.reader)
 }
+
+// handleRTCP - RTP to wall clock mapping from Sender Reports for receivers
+func (c *Conn) handleRTCP(msg *RTCP) {
+	for _, packet := range msg.Packets {
+		sr, ok := packet.(*rtcp.SenderReport)
+		if !ok {
+			continue
+		}
+
+		for _, receiver := range c.Receivers {
+			// RTCP channel is next after RTP channel
+			if receiver.ID+1 == msg.Channel {
+				receiver.SetClock(&core.Clock{
+					Time:      core.NTPToTime(sr.NTPTime),
+					Timestamp: sr.RTPTime,
+					ClockRate: receiver.Codec.ClockRate,
+					Local:     time.Now(),
+				})
+				break
+			}
+		}
+	}
+}
+
+// writer - RTP input for the receiver with the channel, nil if there is no receiver
+func (c *Conn) writer(channelID byte) core.HandlerFunc {
+	if write, ok := c.writers[channelID]; ok {
+		return write
+	}
+
+	for _, receiver := range c.Receivers {
+		if receiver.ID == channelID {
+			write := receiver.WriteRTP
+			if JitterLatency > 0 {
+				write = core.NewJitterBuffer(JitterLatency).Filter(write)
+			}
+			if c.writers == nil {
+				c.writers = map[byte]core.HandlerFunc{}
+			}
+			c.writers[channelID] = write
+			return write
+		}
+	}
+
+	return nil
+}
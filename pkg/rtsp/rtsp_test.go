@@ -3,6 +3,8 @@ package rtsp
 import (
 	"testing"
 
+	"github.com/AlexxIT/go2rtc/pkg/core"
+	"github.com/pion/rtcp"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -159,3 +161,20 @@ a=control:trackID=2
 	assert.Equal(t, "recvonly", medias[0].Direction)
 	assert.Equal(t, "recvonly", medias[1].Direction)
 }
+
+func TestSenderReport(t *testing.T) {
+	receiver := core.NewReceiver(nil, &core.Codec{ClockRate: 90000})
+	receiver.ID = 2
+
+	c := &Conn{}
+	c.Receivers = []*core.Receiver{receiver}
+	c.handleRTCP(&RTCP{Channel: 3, Packets: []rtcp.Packet{
+		&rtcp.SenderReport{NTPTime: 0xE93DFBA580000000, RTPTime: 1000},
+	}})
+
+	clock := receiver.Clock()
+	assert.NotNil(t, clock)
+	assert.Equal(t, int64(1704164645), clock.Time.Unix())
+	assert.Equal(t, uint32(1000), clock.Timestamp)
+	assert.Equal(t, uint32(90000), clock.ClockRate)
+}
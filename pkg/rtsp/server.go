@@ -31,6 +31,14 @@ func (c *Conn) Auth(username, password string) {
 	c.auth = tcp.NewAuth(info)
 }
 
+// Authorize - override auth check result, for example after access token check
+func (c *Conn) Authorize(ok bool) {
+	if ok {
+		c.auth = nil
+	}
+	c.denied = !ok
+}
+
 func (c *Conn) Accept() error {
 	for {
 		req, err := c.ReadRequest()
@@ -45,7 +53,7 @@ func (c *Conn) Accept() error {
 
 		c.Fire(req)
 
-		if !c.auth.Validate(req) {
+		if c.denied || !c.auth.Validate(req) {
 			res := &tcp.Response{
 				Status:  "401 Unauthorized",
 				Header:  map[string][]string{"Www-Authenticate": {`Basic realm="go2rtc"`}},
@@ -107,6 +115,9 @@ func (c *Conn) Accept() error {
 					Status:  "404 Not Found",
 					Request: req,
 				}
+				if c.Reject != "" {
+					res.Status = c.Reject
+				}
 				return c.WriteResponse(res)
 			}
 
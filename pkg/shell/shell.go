@@ -40,11 +40,16 @@ func QuoteSplit(s string) []string {
 }
 
 // ReplaceEnvVars - support format ${CAMERA_PASSWORD} and ${RTSP_USER:admin}
+// and secrets references ${file:/run/secrets/x} and ${enc:base64}
 func ReplaceEnvVars(text string) string {
 	re := regexp.MustCompile(`\${([^}{]+)}`)
 	return re.ReplaceAllStringFunc(text, func(match string) string {
 		key := match[2 : len(match)-1]
 
+		if value, ok := resolveSecret(key); ok {
+			return value
+		}
+
 		var def string
 		var dok bool
 
@@ -3,6 +3,7 @@ package webrtc
 import (
 	"net"
 	"slices"
+	"time"
 
 	"github.com/pion/interceptor"
 	"github.com/pion/webrtc/v3"
@@ -12,6 +13,9 @@ import (
 // https://ffmpeg.org/ffmpeg-all.html#Muxer
 const ReceiveMTU = 1472
 
+// JitterLatency - reorder incoming RTP packets with this latency, 0 - disabled
+var JitterLatency time.Duration
+
 func NewAPI() (*webrtc.API, error) {
 	return NewServerAPI("", "", nil)
 }
@@ -93,6 +93,11 @@ func NewConn(pc *webrtc.PeerConnection) *Conn {
 			}()
 		}
 
+		write := track.WriteRTP
+		if JitterLatency > 0 {
+			write = core.NewJitterBuffer(JitterLatency).Filter(write)
+		}
+
 		for {
 			b := make([]byte, ReceiveMTU)
 			n, _, err := remote.Read(b)
@@ -111,7 +116,7 @@ func NewConn(pc *webrtc.PeerConnection) *Conn {
 				continue
 			}
 
-			track.WriteRTP(packet)
+			write(packet)
 		}
 	})
 